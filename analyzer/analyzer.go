@@ -6,13 +6,17 @@ import (
 
 var Filter vcs.LanguageFilter
 
+//contribution of every developer, without file contents (e.g. imported logs) only files and lines are compared
 func ContributionData(repo *vcs.Repository) (rawData map[string]map[string]float64) {
 
 	rawData = map[string]map[string]float64{}
 
 	for _, dev := range repo.Developers {
 
-		complexityDiff := CalcComplexityDiff(dev)
+		complexityDiff := ComplexityDiff{}
+		if repo.HasContents() {
+			complexityDiff = CalcComplexityDiff(dev)
+		}
 		fileDiff := dev.FileDiff()
 		lineDiff := dev.LineDiff()
 
@@ -30,6 +34,10 @@ func ContributionData(repo *vcs.Repository) (rawData map[string]map[string]float
 				"cyclo_increased": float64(complexityDiff.CycloIncreased),
 				"cyclo_decreased": float64(complexityDiff.CycloDecreased),
 			}
+			if repo.HasContents() == false {
+				delete(rawData[dev.Id], "cyclo_increased")
+				delete(rawData[dev.Id], "cyclo_decreased")
+			}
 		}
 	}
 
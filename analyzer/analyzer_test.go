@@ -0,0 +1,72 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jochil/scabov/vcs"
+)
+
+/*
+alice adds two files, bob changes one of them and removes the other one and
+moves the changed file at last together with carol (as co-author)
+*/
+func buildContributionRepository() *vcs.Repository {
+
+	b := vcs.NewBuilder()
+	add := b.Commit(vcs.CommitSpec{Author: "Alice <alice@example.com>", Date: testDate(1), Message: "add a and b",
+		Write: map[string]string{"a.php": "f=1\n", "b.php": "g=1\n"}})
+	change := b.Commit(vcs.CommitSpec{Author: "Bob <bob@example.com>", Date: testDate(2), Message: "change a, remove b",
+		Parents: []*vcs.Commit{add}, Write: map[string]string{"a.php": "f=2\nh=1\n"}, Remove: []string{"b.php"}})
+	b.Commit(vcs.CommitSpec{Author: "Bob <bob@example.com>", Date: testDate(3), Message: "move a\n\nCo-authored-by: Carol <carol@example.com>",
+		Parents: []*vcs.Commit{change}, Rename: map[string]string{"a.php": "c.php"}})
+
+	repo := b.Repository()
+	cacheTestFunctions(repo)
+	return repo
+}
+
+func TestContributionData(t *testing.T) {
+
+	useTestFilter()
+	repo := buildContributionRepository()
+
+	expected := map[string]map[string]float64{
+		"alice@example.com": {"files_added": 2, "files_removed": 0, "files_changed": 0,
+			"lines_added": 2, "lines_removed": 0, "cyclo_increased": 0, "cyclo_decreased": 0},
+		"bob@example.com": {"files_added": 0, "files_removed": 1, "files_changed": 2,
+			"lines_added": 2, "lines_removed": 2, "cyclo_increased": 0, "cyclo_decreased": 0},
+	}
+	if data := ContributionData(repo); reflect.DeepEqual(data, expected) == false {
+		t.Errorf("expected %v, got %v", expected, data)
+	}
+
+	//without contents only files and lines are compared
+	repo.System = vcs.GITLOG
+	defer func() { repo.System = vcs.MEMORY }()
+	for id, row := range ContributionData(repo) {
+		if _, exists := row["cyclo_increased"]; exists || len(row) != 5 {
+			t.Errorf("expected only files and lines for %s, got %v", id, row)
+		}
+	}
+}
+
+func TestContributionDataSplit(t *testing.T) {
+
+	useTestFilter()
+	vcs.CreditPolicy = vcs.CreditSplit
+	defer func() { vcs.CreditPolicy = vcs.CreditAuthor }()
+	repo := buildContributionRepository()
+
+	//bob and carol share the move
+	data := ContributionData(repo)
+	if len(data) != 3 {
+		t.Fatalf("expected the data of 3 developers, got %v", data)
+	}
+	if changed := data["bob@example.com"]["files_changed"]; changed != 2 {
+		t.Errorf("expected 2 changed files for bob, got %v", changed)
+	}
+	if changed := data["carol@example.com"]["files_changed"]; changed != 1 {
+		t.Errorf("expected 1 changed file for carol, got %v", changed)
+	}
+}
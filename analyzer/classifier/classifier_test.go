@@ -0,0 +1,47 @@
+package classifier
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQCorrelationCoefficient(t *testing.T) {
+
+	matrix := QCorrelationCoefficient(map[string]map[string]float64{
+		"a": {"x": 1, "y": 2, "z": 3},
+		"b": {"x": 2, "y": 4, "z": 6},
+		"c": {"x": 3, "y": 2, "z": 1},
+	})
+
+	//correlated patterns have the smallest proximity
+	expected := map[[2]string]float64{{"a", "a"}: -1, {"a", "b"}: -1, {"a", "c"}: 1, {"b", "c"}: 1}
+	for pair, value := range expected {
+		if proximity := matrix[pair[0]][pair[1]]; math.Abs(proximity-value) > 1e-9 {
+			t.Errorf("expected proximity %v of %s and %s, got %v", value, pair[0], pair[1], proximity)
+		}
+		if matrix[pair[0]][pair[1]] != matrix[pair[1]][pair[0]] {
+			t.Errorf("proximity of %s and %s is not symmetric", pair[0], pair[1])
+		}
+	}
+}
+
+func TestLinkage(t *testing.T) {
+
+	groups := convertToGroups(map[string]map[string]float64{
+		"a": {"b": 1, "c": 5},
+		"b": {"a": 1, "c": 3},
+		"c": {"a": 5, "b": 3},
+	})
+
+	groups, merged := Linkage(groups, completeLinkageMode)
+	if len(groups) != 2 || len(merged.Objects) != 2 {
+		t.Fatalf("expected a and b to be merged, got %v", groups)
+	}
+
+	//complete linkage keeps the largest proximity to the merged objects
+	for _, group := range groups {
+		if group != merged && group.Proximites[merged] != 5 {
+			t.Errorf("expected proximity 5 to the merged group, got %v", group.Proximites[merged])
+		}
+	}
+}
@@ -71,10 +71,10 @@ func CalcComplexityDiff(dev *vcs.Developer) ComplexityDiff {
 
 	//handle added files
 	for _, file := range dev.AddedFiles() {
-		functions := parser.Functions(file)
+		functions := parseFunctions(parser, file)
 
 		for _, function := range functions {
-			cyclo := CyclomaticComplexity(function.CFG)
+			cyclo := function.Complexity
 			diff.CycloNew = append(diff.CycloNew, cyclo)
 			diff.FuncNodes = append(diff.FuncNodes, function.NumNodes)
 		}
@@ -86,15 +86,15 @@ func CalcComplexityDiff(dev *vcs.Developer) ComplexityDiff {
 		//TODO just handle one parent file, get this working for n-parents
 		if parentFile := file.Parents[0]; parentFile != nil {
 
-			functions := parser.Functions(file)
-			parentFunctions := parser.Functions(parentFile)
+			functions := parseFunctions(parser, file)
+			parentFunctions := parseFunctions(parser, parentFile)
 
 			for name, function := range parentFunctions {
 
-				newCyclo := CyclomaticComplexity(function.CFG)
+				newCyclo := function.Complexity
 
 				if parentFunction, ok := functions[name]; ok {
-					oldCyclo := CyclomaticComplexity(parentFunction.CFG)
+					oldCyclo := parentFunction.Complexity
 
 					if oldCyclo > newCyclo {
 						diff.CycloDecreased++
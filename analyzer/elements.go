@@ -23,6 +23,7 @@ type Function struct {
 	Name       string
 	Parameters []Parameter
 	NumNodes   int
+	Complexity int
 	CFG        *gs.Graph
 	Hash       string
 }
@@ -3,6 +3,7 @@ package analyzer
 import (
 	"fmt"
 	"github.com/jochil/scabov/vcs"
+	"log"
 )
 
 type FunctionHistory struct {
@@ -20,7 +21,7 @@ type FunctionHistory struct {
 
 func NewFunctionHistory(function Function, file string) *FunctionHistory {
 
-	cyclo := CyclomaticComplexity(function.CFG)
+	cyclo := function.Complexity
 	return &FunctionHistory{
 		Name:             function.Name,
 		File:             file,
@@ -35,10 +36,10 @@ func NewFunctionHistory(function Function, file string) *FunctionHistory {
 	}
 }
 
+//function was removed on a branch, functions which do not exist anymore are marked as removed after loading the history
 func (history *FunctionHistory) Remove() {
 	history.lifetime++
 	history.changes++
-	history.removed = true
 }
 
 func (history *FunctionHistory) Change(function Function) {
@@ -47,7 +48,7 @@ func (history *FunctionHistory) Change(function Function) {
 		if history.latestHash != function.Hash {
 			history.latestHash = function.Hash
 			history.latestSize = function.NumNodes
-			history.latestComplexity = CyclomaticComplexity(function.CFG)
+			history.latestComplexity = function.Complexity
 			history.changes++
 		}
 		history.lifetime++
@@ -108,92 +109,313 @@ func (fileHistory FileHistory) Rename(filename string) {
 
 var History = map[string]FileHistory{}
 
+//all function histories in order of their creation
+var functionHistories = []*FunctionHistory{}
+
+//commits which are already part of the history (e.g. restored from a snapshot)
+var historyCommits = map[string]bool{}
+
+//state of a function on a branch, states are never modified but replaced
+type functionState struct {
+	history *FunctionHistory
+	hash    string
+}
+
+//functions of a file on a branch, file states are never modified but replaced
+type fileState map[string]*functionState
+
+//files of a branch after a commit, unchanged file states are shared between branches
+type branchState map[string]fileState
+
+//branch states of read commits, which are still needed for children (or heads)
+var branchStates = map[string]branchState{}
+
+/*
+reads the history of all commits, every commit is read after its parents and
+exactly once, branches are tracked separately and reconciled at merge commits
+*/
 func LoadHistory(repo *vcs.Repository) {
-	readCommit(repo.FirstCommit())
+
+	readSegments([][]*vcs.Commit{repo.TopologicalOrder()}, nil)
+
+	//functions which do not exist in any head are removed
+	alive := map[*FunctionHistory]bool{}
+	for _, head := range repo.Heads() {
+		for _, file := range branchStates[head.Id] {
+			for _, function := range file {
+				alive[function.history] = true
+			}
+		}
+	}
+	for _, history := range functionHistories {
+		history.removed = alive[history] == false
+	}
+
+	buildHistory()
 }
 
-func readCommit(commit *vcs.Commit) {
-	readHistory(commit)
-	for _, child := range commit.Children {
-		readCommit(child)
-		//TODO just a workaround to avoid endless processing... shoud be removed
-		break
+/*
+reads the commits of several segments (e.g. releases), every segment is in
+topological order and the parents of its commits are part of the same or a
+previous segment, done is called after each segment (if set)
+*/
+func readSegments(segments [][]*vcs.Commit, done func(segment int)) {
+
+	commits := []*vcs.Commit{}
+	for _, segment := range segments {
+		commits = append(commits, segment...)
+	}
+	for _, history := range functionHistories {
+		history.removed = false
+	}
+
+	//the states of all read parents are needed to continue (e.g. after a snapshot)
+	for _, commit := range commits {
+		if historyCommits[commit.Id] {
+			continue
+		}
+		for id := range commit.Parents {
+			if _, exists := branchStates[id]; historyCommits[id] && !exists {
+				log.Printf("state of commit %s is unknown, reading the complete history", id)
+				resetHistory()
+				break
+			}
+		}
+	}
+
+	//count children which are not read yet
+	unread := map[string]int{}
+	for _, commit := range commits {
+		if historyCommits[commit.Id] == false {
+			for id := range commit.Parents {
+				unread[id]++
+			}
+		}
+	}
+
+	for n, segment := range segments {
+		for _, commit := range segment {
+			readCommit(commit, unread)
+		}
+		if done != nil {
+			done(n)
+		}
 	}
 }
 
-func readHistory(commit *vcs.Commit) {
-	parser := NewParser()
+//reads a commit based on the states of its parents, which are released if all children are read
+func readCommit(commit *vcs.Commit, unread map[string]int) {
+
+	if historyCommits[commit.Id] {
+		return
+	}
+
+	parents := commit.OrderedParents()
+	state := branchState{}
+	otherStates := []branchState{}
+
+	for n, parent := range parents {
+		unread[parent.Id]--
+		parentState := branchStates[parent.Id]
+
+		if n == 0 {
+			//the state of the last child is taken over, for other children it is copied
+			if unread[parent.Id] == 0 {
+				state = parentState
+			} else {
+				for path, file := range parentState {
+					state[path] = file
+				}
+			}
+		} else {
+			otherStates = append(otherStates, parentState)
+		}
+
+		if unread[parent.Id] == 0 {
+			delete(branchStates, parent.Id)
+		}
+	}
+	if state == nil {
+		state = branchState{}
+	}
 
-	//handle the beat for files that was not part of this commit
-	for filename, fileHistory := range History {
-		_, listed := commit.Files[filename]
-		_, removed := commit.RemovedFiles[filename]
-		_, moved := commit.MovedFiles[filename]
+	readHistory(commit, firstDiff(commit), state, otherStates)
+	branchStates[commit.Id] = state
+	historyCommits[commit.Id] = true
+}
+
+//resets the function history, e.g. before another repository is analyzed
+func ResetHistory() {
+	resetHistory()
+}
+
+func resetHistory() {
+	History = map[string]FileHistory{}
+	functionHistories = []*FunctionHistory{}
+	historyCommits = map[string]bool{}
+	branchStates = map[string]branchState{}
+}
 
-		if listed == false && removed == false && moved == false {
-			fileHistory.Beat()
+//groups the function histories by their latest file, existing functions have precedence
+func buildHistory() {
+	History = map[string]FileHistory{}
+	for _, history := range functionHistories {
+		if _, exists := History[history.File]; exists == false {
+			History[history.File] = FileHistory{}
+		}
+		if existing, exists := History[history.File][history.Name]; !exists || existing.removed || !history.removed {
+			History[history.File][history.Name] = history
 		}
 	}
+}
+
+//returns the changes compared to the first parent
+func firstDiff(commit *vcs.Commit) *vcs.ParentDiff {
+	if len(commit.Diffs) > 0 {
+		return commit.Diffs[0]
+	}
+
+	diff := vcs.NewParentDiff("")
+	diff.RemovedFiles = commit.RemovedFiles
+	diff.ChangedFiles = commit.ChangedFiles
+	diff.AddedFiles = commit.AddedFiles
+	diff.MovedFiles = commit.MovedFiles
+	return diff
+}
+
+/*
+applies the changes of a commit (compared to its first parent) to the state of
+the branch, for merge commits functions only count as changed or removed if
+they differ from all other parents too
+*/
+func readHistory(commit *vcs.Commit, diff *vcs.ParentDiff, state branchState, otherStates []branchState) {
+	parser := NewParser()
+
+	//functions which are already counted within this commit
+	counted := map[*FunctionHistory]bool{}
 
 	//handle moved files
-	for oldFilename, newFilename := range commit.MovedFiles {
+	for oldFilename, newFilename := range diff.MovedFiles {
+		if vcs.ValidPath(newFilename) == false {
+			continue
+		}
 
 		//update history to current filename
-		if _, ok := History[newFilename]; ok == false {
-			fileHistory := History[oldFilename]
-			History[newFilename] = fileHistory
-			fileHistory.Rename(newFilename)
-			delete(History, oldFilename)
-
-			//file was just moved not changed, so handle the beat
-			if _, ok := commit.ChangedFiles[newFilename]; ok == false {
-				fileHistory.Beat()
+		if _, ok := state[newFilename]; ok == false {
+			if file, ok := state[oldFilename]; ok {
+				state[newFilename] = file
+				for _, function := range file {
+					function.history.File = newFilename
+				}
 			}
+			delete(state, oldFilename)
 		}
 	}
 
-	//find modified functions
-	for filename, file := range commit.ChangedFiles {
+	//find new, modified and removed functions
+	changedFiles := map[string]*vcs.File{}
+	for filename, file := range diff.ChangedFiles {
+		changedFiles[filename] = file
+	}
+	for filename, file := range diff.AddedFiles {
+		changedFiles[filename] = file
+	}
+	//functions of a shallow clone boundary start their history there
+	for filename, file := range diff.ExistingFiles {
+		changedFiles[filename] = file
+	}
 
-		if _, ok := History[filename]; ok == false {
-			History[filename] = FileHistory{}
+	for filename, file := range changedFiles {
+		if vcs.ValidPath(filename) == false {
+			continue
 		}
 
-		fileHistory := History[filename]
-		functions := parser.Functions(file)
+		oldFile := state[filename]
+		newFile := fileState{}
+
+		for name, function := range parseFunctions(parser, file) {
+			previous := oldFile[name]
+			unchanged := previous != nil && previous.hash == function.Hash
+
+			//the function could be taken over from a merged branch
+			for _, other := range otherStates {
+				if crt := other[filename][name]; crt != nil {
+					if previous == nil {
+						previous = crt
+					}
+					if crt.hash == function.Hash {
+						previous = crt
+						unchanged = true
+					}
+				}
+			}
 
-		//search for (un)changed function
-		for name, function := range functions {
-			if history, ok := fileHistory[name]; ok {
-				history.Change(function)
-			} else {
-				fileHistory[name] = NewFunctionHistory(function, filename)
+			switch {
+			case previous == nil:
+				history := NewFunctionHistory(function, filename)
+				functionHistories = append(functionHistories, history)
+				newFile[name] = &functionState{history, function.Hash}
+			case unchanged:
+				if counted[previous.history] == false {
+					previous.history.Beat()
+				}
+				newFile[name] = previous
+			default:
+				if counted[previous.history] == false {
+					previous.history.Change(function)
+				}
+				newFile[name] = &functionState{previous.history, function.Hash}
 			}
+			counted[newFile[name].history] = true
 		}
 
-		//search removed functions
-		for name, history := range fileHistory {
-			if _, ok := functions[name]; ok == false {
-				history.Remove()
+		//functions which were already removed on a merged branch do not count
+		for name, function := range oldFile {
+			if _, exists := newFile[name]; !exists && existsInAll(otherStates, filename, name) {
+				removeFunction(function.history, counted)
 			}
 		}
+
+		state[filename] = newFile
 	}
 
 	//handle removed files
-	for filename, _ := range commit.RemovedFiles {
-		fileHistory := History[filename]
-		fileHistory.Remove()
+	for filename := range diff.RemovedFiles {
+		if vcs.ValidPath(filename) == false {
+			continue
+		}
+		for name, function := range state[filename] {
+			if existsInAll(otherStates, filename, name) {
+				removeFunction(function.history, counted)
+			}
+		}
+		delete(state, filename)
 	}
 
-	//find new functions
-	for filename, file := range commit.AddedFiles {
-
-		fileHistory := FileHistory{}
-		History[filename] = fileHistory
+	//handle the beat for all other functions of the branch
+	for _, file := range state {
+		for _, function := range file {
+			if counted[function.history] == false {
+				function.history.Beat()
+				counted[function.history] = true
+			}
+		}
+	}
+}
 
-		functions := parser.Functions(file)
-		for name, function := range functions {
-			fileHistory[name] = NewFunctionHistory(function, filename)
+//checks if a function exists within all states
+func existsInAll(states []branchState, filename string, name string) bool {
+	for _, state := range states {
+		if _, exists := state[filename][name]; !exists {
+			return false
 		}
 	}
+	return true
+}
+
+func removeFunction(history *FunctionHistory, counted map[*FunctionHistory]bool) {
+	if counted[history] == false {
+		history.Remove()
+		counted[history] = true
+	}
 }
@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jochil/scabov/vcs"
+)
+
+/*
+files of the test repositories contain one function per line ("name=hash"),
+they are put into the cache of parsed functions, so the history is tested
+independently of the php parser
+*/
+func cacheTestFunctions(repo *vcs.Repository) {
+	for _, commit := range repo.Commits {
+		for _, file := range commit.Files {
+			content, err := file.Content()
+			if err != nil {
+				continue
+			}
+			functions := map[string]Function{}
+			for _, line := range strings.Fields(content) {
+				fields := strings.SplitN(line, "=", 2)
+				functions[fields[0]] = Function{Name: fields[0], Hash: fields[1]}
+			}
+			parsedFunctions[file.Id] = functions
+		}
+	}
+}
+
+//returns the date of the n-th test commit
+func testDate(n int) time.Time {
+	return time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+//selects php files, all paths are valid
+func useTestFilter() {
+	vcs.Filter = vcs.NewLanguageFilter(vcs.PHP)
+	Filter = vcs.Filter
+}
+
+/*
+a function g is removed on the main branch and changed on a feature branch,
+which adds a function h, the merge keeps the removal and changes f, at last
+the file is renamed
+*/
+func TestLoadHistory(t *testing.T) {
+
+	useTestFilter()
+	ResetHistory()
+	defer ResetHistory()
+
+	b := vcs.NewBuilder()
+	root := b.Commit(vcs.CommitSpec{Author: "alice@example.com", Date: testDate(1), Message: "add f and g",
+		Write: map[string]string{"a.php": "f=1\ng=1\n"}})
+	main := b.Commit(vcs.CommitSpec{Author: "alice@example.com", Date: testDate(2), Message: "remove g",
+		Parents: []*vcs.Commit{root}, Write: map[string]string{"a.php": "f=1\n"}})
+	feature := b.Commit(vcs.CommitSpec{Author: "bob@example.com", Date: testDate(3), Message: "change f, add h",
+		Parents: []*vcs.Commit{root}, Write: map[string]string{"a.php": "f=2\ng=1\nh=1\n"}})
+	feature = b.Commit(vcs.CommitSpec{Author: "bob@example.com", Date: testDate(4), Message: "change h",
+		Parents: []*vcs.Commit{feature}, Write: map[string]string{"a.php": "f=2\ng=1\nh=2\n"}})
+	merge := b.Commit(vcs.CommitSpec{Author: "alice@example.com", Date: testDate(5), Message: "merge feature",
+		Parents: []*vcs.Commit{main, feature}, Write: map[string]string{"a.php": "f=3\nh=2\n"}})
+	b.Commit(vcs.CommitSpec{Author: "alice@example.com", Date: testDate(6), Message: "rename a",
+		Parents: []*vcs.Commit{merge}, Rename: map[string]string{"a.php": "b.php"}})
+
+	repo := b.Repository()
+	cacheTestFunctions(repo)
+	LoadHistory(repo)
+
+	expected := map[string]map[string]struct {
+		lifetime, changes int
+		removed           bool
+	}{
+		//changed on the branch and by the merge
+		"b.php": {"f": {5, 2, false}, "h": {3, 1, false}},
+		//removed on the main branch, the merge does not count as change
+		"a.php": {"g": {3, 1, true}},
+	}
+
+	if len(History) != len(expected) {
+		t.Errorf("expected the history of %d files, got %v", len(expected), History)
+	}
+	for file, functions := range expected {
+		if len(History[file]) != len(functions) {
+			t.Errorf("expected %d functions in %s, got %v", len(functions), file, History[file])
+		}
+		for name, values := range functions {
+			history := History[file][name]
+			if history == nil {
+				t.Errorf("missing history of %s in %s", name, file)
+				continue
+			}
+			if history.lifetime != values.lifetime || history.changes != values.changes || history.removed != values.removed {
+				t.Errorf("expected lifetime %d, %d changes and removed %v for %s in %s, got %d, %d and %v", values.lifetime,
+					values.changes, values.removed, name, file, history.lifetime, history.changes, history.removed)
+			}
+		}
+	}
+}
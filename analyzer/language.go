@@ -13,10 +13,12 @@ func CalcLanguageUsage(dev *vcs.Developer) LanguageUsage {
 	parser := NewParser()
 	langUsage := NewLanguageUsage()
 
-	for _, commit := range dev.Commits {
-		for _, file := range commit.AddedFiles {
+	for commit := range dev.Credits() {
+		for path, file := range commit.Changes().AddedFiles {
 
-			parser.UpdateLanguageUsage(langUsage, file)
+			if vcs.ValidPath(path) {
+				parser.UpdateLanguageUsage(langUsage, file)
+			}
 
 		}
 	}
@@ -0,0 +1,80 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jochil/scabov/analyzer/classifier"
+	"github.com/jochil/scabov/vcs"
+)
+
+/*
+four developers only add files and four developers only remove files (a
+different number of files each), the classification must not put developers
+of both kinds into the same group
+*/
+func TestContributionClassification(t *testing.T) {
+
+	useTestFilter()
+	b := vcs.NewBuilder()
+	var head *vcs.Commit
+	commit := func(spec vcs.CommitSpec) {
+		if head != nil {
+			spec.Parents = []*vcs.Commit{head}
+		}
+		spec.Date = testDate(len(b.Repository().Commits))
+		head = b.Commit(spec)
+	}
+
+	for n := 0; n < 4; n++ {
+		for i := 0; i < 3; i++ {
+			files := map[string]string{}
+			for k := 0; k <= n+i; k++ {
+				files[fmt.Sprintf("src/%d/%d/%d.php", n, i, k)] = fmt.Sprintf("f%d=1\ng=1\nh=1\n", k)
+			}
+			commit(vcs.CommitSpec{Author: fmt.Sprintf("adder%d@example.com", n), Message: "add files", Write: files})
+		}
+	}
+	for n := 0; n < 4; n++ {
+		files := []string{}
+		for path := range b.FilesAt(head) {
+			if len(files) < n+2 {
+				files = append(files, path)
+			}
+		}
+		commit(vcs.CommitSpec{Author: fmt.Sprintf("remover%d@example.com", n), Message: "remove files", Remove: files})
+	}
+
+	repo := b.Repository()
+	cacheTestFunctions(repo)
+	data := ContributionData(repo)
+	if len(data) != 8 {
+		t.Fatalf("expected the data of 8 developers, got %v", data)
+	}
+
+	groups := classifier.ClusterAnalysis(data)
+	if len(groups) < 2 {
+		t.Fatalf("expected at least 2 groups, got %v", groups)
+	}
+
+	grouped := map[string]bool{}
+	for _, group := range groups {
+		for _, id := range group.Objects {
+			if grouped[id] {
+				t.Errorf("%s is part of several groups", id)
+			}
+			grouped[id] = true
+			if strings.HasPrefix(id, "adder") != strings.HasPrefix(group.Objects[0], "adder") {
+				t.Errorf("expected adders and removers in different groups, got %v", group)
+			}
+		}
+	}
+	if len(grouped) != len(data) {
+		t.Errorf("expected all developers to be grouped, got %v", groups)
+	}
+
+	if homogeneity := CalcHomogeneity(groups); homogeneity <= 0 || homogeneity >= 1 {
+		t.Errorf("expected a homogeneity between 0 and 1, got %v", homogeneity)
+	}
+}
@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"github.com/jochil/scabov/vcs"
+	"path"
+	"sort"
+)
+
+//lines of a file or directory by their last author
+type Ownership struct {
+	Path   string
+	Lines  int
+	Owners map[string]int
+}
+
+func NewOwnership(path string) *Ownership {
+	return &Ownership{Path: path, Lines: 0, Owners: map[string]int{}}
+}
+
+func (ownership *Ownership) add(other *Ownership) {
+	ownership.Lines += other.Lines
+	for id, lines := range other.Owners {
+		ownership.Owners[id] += lines
+	}
+}
+
+//share of the lines owned by a developer
+func (ownership *Ownership) Share(devId string) float64 {
+	if ownership.Lines == 0 {
+		return 0.0
+	}
+	return float64(ownership.Owners[devId]) / float64(ownership.Lines)
+}
+
+//returns the developer owning most of the lines and their share (ties are resolved by the id)
+func (ownership *Ownership) PrimaryOwner() (string, float64) {
+
+	ids := []string{}
+	for id := range ownership.Owners {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	primary := ""
+	for _, id := range ids {
+		if primary == "" || ownership.Owners[id] > ownership.Owners[primary] {
+			primary = id
+		}
+	}
+	return primary, ownership.Share(primary)
+}
+
+/*
+probability that two random lines have different owners (1 - sum of the squared
+shares), 0 for a single owner and close to 1 for many owners with few lines each
+*/
+func (ownership *Ownership) Fragmentation() float64 {
+	if ownership.Lines == 0 {
+		return 0.0
+	}
+
+	sum := 0.0
+	for id := range ownership.Owners {
+		share := ownership.Share(id)
+		sum += share * share
+	}
+	return 1.0 - sum
+}
+
+//calculates the ownership of all analyzed files at a revision by blaming them
+func FileOwnership(repo *vcs.Repository, revision *vcs.Commit) (map[string]*Ownership, error) {
+
+	paths := []string{}
+	for path := range repo.FilesAt(revision) {
+		if vcs.ValidPath(path) {
+			paths = append(paths, path)
+		}
+	}
+
+	blames, err := repo.BlameFiles(revision, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerships := map[string]*Ownership{}
+	for path, lines := range blames {
+		ownership := NewOwnership(path)
+		for _, line := range lines {
+			ownership.Lines++
+			ownership.Owners[line.Commit.Developer.Id]++
+		}
+		ownerships[path] = ownership
+	}
+	return ownerships, nil
+}
+
+//sums up the ownership of files for all of their directories ("." for the root)
+func DirectoryOwnership(files map[string]*Ownership) map[string]*Ownership {
+
+	directories := map[string]*Ownership{}
+	for filename, file := range files {
+		for dir := path.Dir(filename); ; dir = path.Dir(dir) {
+			if _, exists := directories[dir]; exists == false {
+				directories[dir] = NewOwnership(dir)
+			}
+			directories[dir].add(file)
+
+			if dir == "." || dir == "/" {
+				break
+			}
+		}
+	}
+	return directories
+}
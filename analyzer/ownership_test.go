@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jochil/scabov/vcs"
+)
+
+/*
+bob changes a line of alice on the main branch, carol adds a line on a feature
+branch, which is merged by alice
+*/
+func TestFileOwnership(t *testing.T) {
+
+	useTestFilter()
+	vcs.KeepHunks = true
+	defer func() { vcs.KeepHunks = false }()
+
+	b := vcs.NewBuilder()
+	root := b.Commit(vcs.CommitSpec{Author: "alice@example.com", Date: testDate(1), Message: "add a and b",
+		Write: map[string]string{"src/a.php": "f=1\ng=1\nh=1\n", "b.php": "i=1\n"}})
+	main := b.Commit(vcs.CommitSpec{Author: "bob@example.com", Date: testDate(2), Message: "change g",
+		Parents: []*vcs.Commit{root}, Write: map[string]string{"src/a.php": "f=1\ng=2\nh=1\n"}})
+	feature := b.Commit(vcs.CommitSpec{Author: "carol@example.com", Date: testDate(3), Message: "add k",
+		Parents: []*vcs.Commit{root}, Write: map[string]string{"src/a.php": "f=1\ng=1\nh=1\nk=1\n"}})
+	merge := b.Commit(vcs.CommitSpec{Author: "alice@example.com", Date: testDate(4), Message: "merge feature",
+		Parents: []*vcs.Commit{main, feature}, Write: map[string]string{"src/a.php": "f=1\ng=2\nh=1\nk=1\n"}})
+
+	files, err := FileOwnership(b.Repository(), merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := files["src/a.php"]
+	if len(files) != 2 || file == nil {
+		t.Fatalf("expected the ownership of 2 files, got %v", files)
+	}
+	expected := map[string]int{"alice@example.com": 2, "bob@example.com": 1, "carol@example.com": 1}
+	for id, lines := range expected {
+		if file.Owners[id] != lines {
+			t.Errorf("expected %d lines of %s, got %d", lines, id, file.Owners[id])
+		}
+	}
+	if owner, share := file.PrimaryOwner(); owner != "alice@example.com" || share != 0.5 {
+		t.Errorf("expected alice as primary owner with a share of 0.5, got %s and %v", owner, share)
+	}
+	if fragmentation := file.Fragmentation(); math.Abs(fragmentation-0.625) > 1e-9 {
+		t.Errorf("expected a fragmentation of 0.625, got %v", fragmentation)
+	}
+
+	directories := DirectoryOwnership(files)
+	if src := directories["src"]; src == nil || src.Lines != 4 {
+		t.Errorf("expected 4 lines within src, got %v", src)
+	}
+	if root := directories["."]; root == nil || root.Lines != 5 || root.Owners["alice@example.com"] != 3 {
+		t.Errorf("expected 5 lines (3 of alice) within the root, got %v", root)
+	}
+}
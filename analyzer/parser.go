@@ -28,13 +28,36 @@ func NewParser() Parser {
 	return nil
 }
 
+//parsed functions by file id, every file version is parsed only once
+var parsedFunctions = map[string]map[string]Function{}
+
+//returns the (cached) functions of a file, the control flow graphs are not cached
+func parseFunctions(parser Parser, file *vcs.File) map[string]Function {
+
+	if functions, exists := parsedFunctions[file.Id]; exists {
+		return functions
+	}
+
+	functions := parser.Functions(file)
+	for name, function := range functions {
+		function.CFG = nil
+		functions[name] = function
+	}
+	parsedFunctions[file.Id] = functions
+	return functions
+}
+
 // struct for the php parser (implemented against Parser interface)
 type PHPParser struct {
 }
 
 //TODO add to interface
 func (parser *PHPParser) UpdateLanguageUsage(langUsage LanguageUsage, file *vcs.File) {
-	code := file.Content()
+	code, err := file.Content()
+	if err != nil {
+		log.Printf("unable to read language usage: %s", err)
+		return
+	}
 
 	lex := lexer.NewLexer(code)
 	for {
@@ -106,7 +129,11 @@ func (parser *PHPParser) Elements(file *vcs.File) []Element {
 }
 
 func (parser *PHPParser) parseFile(file *vcs.File) []ast.Node {
-	code := file.Content()
+	code, err := file.Content()
+	if err != nil {
+		log.Printf("unable to parse file: %s", err)
+		return []ast.Node{}
+	}
 	realParser := php.NewParser(code)
 	nodes, err := realParser.Parse()
 
@@ -137,6 +164,7 @@ func (parser *PHPParser) readFunction(name string, body *ast.Block) Function {
 	element := Function{}
 	element.Name = name
 	element.CFG = parser.buildCFG(body)
+	element.Complexity = CyclomaticComplexity(element.CFG)
 
 	if body != nil {
 		element.NumNodes = countNodes(body.Children())
@@ -0,0 +1,84 @@
+package analyzer
+
+import (
+	"github.com/jochil/scabov/analyzer/classifier"
+	"github.com/jochil/scabov/vcs"
+)
+
+//metrics of the commits of a single release
+type ReleaseMetrics struct {
+	Release                 *vcs.Release
+	Developers              int
+	Stability               float64
+	StyleHomogeneity        float64
+	ContributionHomogeneity float64
+}
+
+/*
+calculates the function stability and both homogeneity values for every release,
+the homogeneity is based on the developers and commits of the release only
+*/
+func CalcReleaseMetrics(repo *vcs.Repository) []*ReleaseMetrics {
+
+	releases := repo.Releases()
+	stabilities := calcReleaseStability(releases)
+
+	metrics := []*ReleaseMetrics{}
+	for n, release := range releases {
+		subset := repo.Subset(release.Commits)
+
+		metrics = append(metrics, &ReleaseMetrics{
+			Release:                 release,
+			Developers:              len(subset.Developers),
+			Stability:               stabilities[n],
+			StyleHomogeneity:        CalcHomogeneity(classifier.ClusterAnalysis(StyleData(subset))),
+			ContributionHomogeneity: CalcHomogeneity(classifier.ClusterAnalysis(ContributionData(subset))),
+		})
+	}
+	return metrics
+}
+
+/*
+reads the function history release by release, the stability of a release only
+covers the lifetime and changes of the functions within the release, the
+history of the repository (e.g. used by CalcFunctionStability) is kept
+*/
+func calcReleaseStability(releases []*vcs.Release) []float64 {
+
+	history, histories, commits, states := History, functionHistories, historyCommits, branchStates
+	defer func() {
+		History, functionHistories, historyCommits, branchStates = history, histories, commits, states
+	}()
+	resetHistory()
+
+	segments := [][]*vcs.Commit{}
+	for _, release := range releases {
+		segments = append(segments, release.TopologicalOrder())
+	}
+
+	//lifetime and changes at the end of the previous release
+	type counters struct {
+		lifetime int
+		changes  int
+	}
+	previous := map[*FunctionHistory]counters{}
+	stabilities := make([]float64, len(releases))
+
+	readSegments(segments, func(n int) {
+		count, sum := 0, 0.0
+		for _, crt := range functionHistories {
+			lifetime := crt.lifetime - previous[crt].lifetime
+			changes := crt.changes - previous[crt].changes
+			if lifetime > 0 {
+				count++
+				sum += float64(lifetime-changes) / float64(lifetime)
+			}
+			previous[crt] = counters{crt.lifetime, crt.changes}
+		}
+		if count > 0 {
+			stabilities[n] = sum / float64(count)
+		}
+	})
+
+	return stabilities
+}
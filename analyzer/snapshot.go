@@ -0,0 +1,180 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/jochil/scabov/vcs"
+	"log"
+)
+
+//key of the analyzer data within the repository snapshot
+const snapshotKey = "analyzer"
+
+//version of the analyzer data, data of other versions is ignored
+const snapshotVersion = 2
+
+//parsed functions and function history, persisted within the repository snapshot
+type analyzerSnapshot struct {
+	Version        int
+	Functions      map[string]map[string]snapshotFunction
+	Histories      []snapshotFunctionHistory
+	BranchStates   map[string]map[string]map[string]snapshotFunctionState
+	HistoryCommits map[string]bool
+}
+
+type snapshotFunction struct {
+	Name       string
+	Parameters []Parameter
+	NumNodes   int
+	Complexity int
+	Hash       string
+}
+
+type snapshotFunctionHistory struct {
+	Name             string
+	File             string
+	Lifetime         int
+	Changes          int
+	Removed          bool
+	LatestHash       string
+	FirstComplexity  int
+	LatestComplexity int
+	FirstSize        int
+	LatestSize       int
+}
+
+//state of a function on a branch, the history is referenced by its index
+type snapshotFunctionState struct {
+	History int
+	Hash    string
+}
+
+//restores parsed functions and the function history of a previous run
+func RestoreSnapshot(repo *vcs.Repository) error {
+
+	data := repo.SnapshotData(snapshotKey)
+	if data == nil {
+		return nil
+	}
+
+	snapshot := analyzerSnapshot{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snapshot); err != nil {
+		return err
+	}
+	if snapshot.Version != snapshotVersion {
+		log.Printf("ignoring analyzer data of version %d", snapshot.Version)
+		return nil
+	}
+
+	for id, functions := range snapshot.Functions {
+		parsedFunctions[id] = map[string]Function{}
+		for name, crt := range functions {
+			parsedFunctions[id][name] = Function{
+				Name:       crt.Name,
+				Parameters: crt.Parameters,
+				NumNodes:   crt.NumNodes,
+				Complexity: crt.Complexity,
+				Hash:       crt.Hash,
+			}
+		}
+	}
+
+	functionHistories = []*FunctionHistory{}
+	for _, crt := range snapshot.Histories {
+		functionHistories = append(functionHistories, &FunctionHistory{
+			Name:             crt.Name,
+			File:             crt.File,
+			lifetime:         crt.Lifetime,
+			changes:          crt.Changes,
+			removed:          crt.Removed,
+			latestHash:       crt.LatestHash,
+			firstComplexity:  crt.FirstComplexity,
+			latestComplexity: crt.LatestComplexity,
+			firstSize:        crt.FirstSize,
+			latestSize:       crt.LatestSize,
+		})
+	}
+
+	//function states are shared between branches, so they are shared again after restoring
+	states := map[snapshotFunctionState]*functionState{}
+	for id, files := range snapshot.BranchStates {
+		state := branchState{}
+		for filename, functions := range files {
+			state[filename] = fileState{}
+			for name, crt := range functions {
+				if _, exists := states[crt]; !exists {
+					states[crt] = &functionState{functionHistories[crt.History], crt.Hash}
+				}
+				state[filename][name] = states[crt]
+			}
+		}
+		branchStates[id] = state
+	}
+
+	for id := range snapshot.HistoryCommits {
+		historyCommits[id] = true
+	}
+
+	buildHistory()
+	return nil
+}
+
+//stores parsed functions and the function history within the repository snapshot
+func UpdateSnapshot(repo *vcs.Repository) error {
+
+	snapshot := analyzerSnapshot{
+		Version:        snapshotVersion,
+		Functions:      map[string]map[string]snapshotFunction{},
+		Histories:      []snapshotFunctionHistory{},
+		BranchStates:   map[string]map[string]map[string]snapshotFunctionState{},
+		HistoryCommits: historyCommits,
+	}
+
+	for id, functions := range parsedFunctions {
+		snapshot.Functions[id] = map[string]snapshotFunction{}
+		for name, function := range functions {
+			snapshot.Functions[id][name] = snapshotFunction{
+				Name:       function.Name,
+				Parameters: function.Parameters,
+				NumNodes:   function.NumNodes,
+				Complexity: function.Complexity,
+				Hash:       function.Hash,
+			}
+		}
+	}
+
+	index := map[*FunctionHistory]int{}
+	for n, history := range functionHistories {
+		index[history] = n
+		snapshot.Histories = append(snapshot.Histories, snapshotFunctionHistory{
+			Name:             history.Name,
+			File:             history.File,
+			Lifetime:         history.lifetime,
+			Changes:          history.changes,
+			Removed:          history.removed,
+			LatestHash:       history.latestHash,
+			FirstComplexity:  history.firstComplexity,
+			LatestComplexity: history.latestComplexity,
+			FirstSize:        history.firstSize,
+			LatestSize:       history.latestSize,
+		})
+	}
+
+	for id, state := range branchStates {
+		files := map[string]map[string]snapshotFunctionState{}
+		for filename, file := range state {
+			files[filename] = map[string]snapshotFunctionState{}
+			for name, function := range file {
+				files[filename][name] = snapshotFunctionState{index[function.history], function.hash}
+			}
+		}
+		snapshot.BranchStates[id] = files
+	}
+
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(snapshot); err != nil {
+		return err
+	}
+	repo.SetSnapshotData(snapshotKey, buffer.Bytes())
+	return nil
+}
@@ -12,13 +12,21 @@ import (
 
 type xmlRoot struct {
 	XMLName        xml.Name            `xml:"result"`
+	Repository     xmlRepository       `xml:"repository"`
 	Metrics        xmlMetrics          `xml:"metrics"`
 	Files          []xmlFile           `xml:"files>file"`
+	Ownership      []xmlOwnership      `xml:"ownership>file"`
+	Releases       []xmlRelease        `xml:"releases>release"`
 	Classification []xmlClassification `xml:"classifications>classification"`
 }
 
 var root xmlRoot = xmlRoot{}
 
+//clears all saved results, e.g. before the results of another repository are saved
+func Reset() {
+	root = xmlRoot{}
+}
+
 func SaveFile(file *os.File) {
 
 	//save file
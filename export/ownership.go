@@ -0,0 +1,42 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/jochil/scabov/analyzer"
+	"sort"
+)
+
+type xmlOwnership struct {
+	XMLName       xml.Name `xml:"file"`
+	Path          []byte   `xml:",innerxml"`
+	Lines         int      `xml:"lines"`
+	Owner         string   `xml:"owner"`
+	Share         string   `xml:"share"`
+	Owners        int      `xml:"owners"`
+	Fragmentation string   `xml:"fragmentation"`
+}
+
+func SaveOwnership(ownerships map[string]*analyzer.Ownership) {
+
+	paths := []string{}
+	for path := range ownerships {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	//create xml structure
+	for _, path := range paths {
+		ownership := ownerships[path]
+		owner, share := ownership.PrimaryOwner()
+
+		root.Ownership = append(root.Ownership, xmlOwnership{
+			Path:          []byte("<path><![CDATA[" + path + "]]></path>"),
+			Lines:         ownership.Lines,
+			Owner:         owner,
+			Share:         fmt.Sprintf("%.4f", share),
+			Owners:        len(ownership.Owners),
+			Fragmentation: fmt.Sprintf("%.4f", ownership.Fragmentation()),
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/jochil/scabov/analyzer"
+	"time"
+)
+
+type xmlRelease struct {
+	XMLName                 xml.Name `xml:"release"`
+	Name                    string   `xml:"name,attr"`
+	Commit                  string   `xml:"commit,attr,omitempty"`
+	Date                    string   `xml:"date,attr,omitempty"`
+	Commits                 int      `xml:"commits"`
+	Developers              int      `xml:"developers"`
+	Stability               string   `xml:"stability"`
+	StyleHomogeneity        string   `xml:"homogeneity>style"`
+	ContributionHomogeneity string   `xml:"homogeneity>contribution"`
+}
+
+func SaveReleaseMetrics(metrics []*analyzer.ReleaseMetrics) {
+
+	for _, crt := range metrics {
+		xmlRelease := xmlRelease{
+			Name:                    crt.Release.Name(),
+			Commits:                 len(crt.Release.Commits),
+			Developers:              crt.Developers,
+			Stability:               fmt.Sprintf("%.4f", crt.Stability),
+			StyleHomogeneity:        fmt.Sprintf("%.4f", crt.StyleHomogeneity),
+			ContributionHomogeneity: fmt.Sprintf("%.4f", crt.ContributionHomogeneity),
+		}
+		if tag := crt.Release.Tag; tag != nil {
+			xmlRelease.Commit = tag.Commit.Id
+			xmlRelease.Date = tag.Date.Format(time.RFC3339)
+		}
+
+		root.Releases = append(root.Releases, xmlRelease)
+	}
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"encoding/xml"
+	"github.com/jochil/scabov/vcs"
+)
+
+type xmlRepository struct {
+	XMLName    xml.Name       `xml:"repository"`
+	System     string         `xml:"system,attr"`
+	Commits    int            `xml:"commits"`
+	Developers int            `xml:"developers"`
+	Submodules []xmlSubmodule `xml:"submodules>submodule"`
+	Excluded   []xmlExcluded  `xml:"excluded>file"`
+}
+
+//file excluded by its content (e.g. binary or generated)
+type xmlExcluded struct {
+	Path   string `xml:"path,attr"`
+	Reason string `xml:"reason,attr"`
+}
+
+type xmlSubmodule struct {
+	Path   string `xml:"path,attr"`
+	Url    string `xml:"url,attr,omitempty"`
+	Commit string `xml:"commit,attr"`
+	//file with the results of the submodule, if it was analyzed
+	Result string `xml:"result,attr,omitempty"`
+}
+
+func SaveRepositoryInfo(repo *vcs.Repository) {
+
+	root.Repository = xmlRepository{
+		System:     repo.SystemName(),
+		Commits:    len(repo.Commits),
+		Developers: len(repo.Developers),
+	}
+
+	for _, path := range repo.ExcludedPaths() {
+		root.Repository.Excluded = append(root.Repository.Excluded, xmlExcluded{path, repo.Excluded[path]})
+	}
+}
+
+//saves the submodules of the repository and the result files of the analyzed ones by path
+func SaveSubmodules(submodules []*vcs.Submodule, results map[string]string) {
+
+	root.Repository.Submodules = nil
+	for _, submodule := range submodules {
+		root.Repository.Submodules = append(root.Repository.Submodules, xmlSubmodule{
+			Path:   submodule.Path,
+			Url:    submodule.Url,
+			Commit: submodule.Commit,
+			Result: results[submodule.Path],
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"github.com/jochil/scabov/analyzer"
 	"github.com/jochil/scabov/analyzer/classifier"
 	"github.com/jochil/scabov/export"
@@ -10,16 +11,46 @@ import (
 	"log"
 	"os"
 	"path"
+	"strings"
+	"time"
 )
 
 var (
 	//parameters
-	repoPath       = flag.String("p", "", "(remote) path to an vcs repository")
+	repoPath       = flag.String("p", "", "(remote) path to an vcs repository or a git log export (git log --parents --numstat --summary)")
 	verbose        = flag.Bool("v", false, "activate verbose output")
 	language       = flag.String("l", "", "select programming language for analysis")
 	metrics        = flag.Bool("m", false, "activate metrics calculation")
 	classification = flag.Bool("c", false, "activate developer classification")
+	releases       = flag.Bool("releases", false, "activate metrics calculation for every release (tag)")
+	ownership      = flag.Bool("ownership", false, "activate code ownership calculation (blame of all analyzed files)")
 	outputFilename = flag.String("o", "", "select output file")
+	gitBackend     = flag.String("b", "", "select git backend (libgit2, go-git)")
+	revision       = flag.String("r", "HEAD", "select branch, tag, commit or range (A..B) to analyze")
+	since          = flag.String("since", "", "analyze only commits since date (YYYY-MM-DD)")
+	until          = flag.String("until", "", "analyze only commits until date (YYYY-MM-DD)")
+	cloneDepth     = flag.Int("depth", 0, "clone only the latest commits of remote git repositories (shallow clone)")
+	cloneSince     = flag.String("shallow-since", "", "clone only commits since date of remote git repositories (YYYY-MM-DD)")
+	mailmap        = flag.Bool("mailmap", true, "merge developer identities by .mailmap")
+	aliasFile      = flag.String("aliases", "", "select file with additional identity rules (.mailmap format)")
+	mergeNames     = flag.Bool("merge-names", false, "merge developers with the same name")
+	normalizeMails = flag.Bool("normalize-emails", false, "merge developers by normalized email (lower case, without +tag)")
+	creditPolicy   = flag.String("credit", vcs.CreditAuthor, "select credited developers of commits (author, committer, split, shared)")
+	mergePolicy    = flag.String("merges", vcs.MergeAll, "select credited changes of merge commits (all, ignore, first-parent, conflicts)")
+	importPolicy   = flag.String("imports", vcs.ImportInclude, "select handling of git submodules and subtree merges (include, skip, recurse)")
+	includePaths   = flag.String("include", "", "analyze only paths matching these globs (comma separated, e.g. \"src/**\")")
+	excludePaths   = flag.String("exclude", "", "ignore paths matching these globs (comma separated, e.g. \"vendor/,*.min.php\")")
+	pathConfig     = flag.String("paths", "", "select file with include/exclude path rules")
+	generated      = flag.Bool("detect-generated", true, "exclude binary, generated and minified files (detected by their content)")
+	keepHunks      = flag.Bool("hunks", false, "keep the changed lines of all commits (needed for line based analyses)")
+	workers        = flag.Int("workers", 0, "select number of parallel workers for loading git commits (default number of cpus)")
+	cacheSize      = flag.Int64("cache-size", 64, "select size of the in-memory file cache (MB)")
+	diskCache      = flag.Bool("disk-cache", false, "activate compressed file cache within the workspace")
+	workspaceRoot  = flag.String("w", "", "select directory for workspaces (default ./workspace)")
+	keepWorkspace  = flag.Bool("keep-workspace", false, "keep the workspace (clone, cache) after the analysis (always kept with -snapshot)")
+	listWorkspaces = flag.Bool("list-workspaces", false, "list all workspaces and exit")
+	snapshotFile   = flag.String("snapshot", "", "select snapshot file, only commits added since the last run are analyzed")
+	pruneAge       = flag.Duration("prune-workspaces", 0, "delete workspaces unused for the given duration (e.g. 720h) and exit")
 
 	//local vars
 	repo                                                  *vcs.Repository
@@ -41,6 +72,58 @@ func main() {
 	filter := vcs.NewLanguageFilter(*language)
 	vcs.Filter = filter
 	analyzer.Filter = filter
+	vcs.GitBackend = *gitBackend
+	vcs.Revision = *revision
+	vcs.Since = parseDate(*since, false)
+	vcs.Until = parseDate(*until, true)
+	vcs.CloneDepth = *cloneDepth
+	vcs.CloneSince = parseDate(*cloneSince, false)
+	vcs.UseMailmap = *mailmap
+	vcs.AliasFile = *aliasFile
+	vcs.MergeByName = *mergeNames
+	vcs.NormalizeEmails = *normalizeMails
+
+	switch *mergePolicy {
+	case vcs.MergeAll, vcs.MergeIgnore, vcs.MergeFirstParent, vcs.MergeConflicts:
+		vcs.MergePolicy = *mergePolicy
+	default:
+		log.Fatalf("unknown merge policy %q, e.g.: -merges first-parent", *mergePolicy)
+	}
+
+	switch *importPolicy {
+	case vcs.ImportInclude, vcs.ImportSkip, vcs.ImportRecurse:
+		vcs.ImportPolicy = *importPolicy
+	default:
+		log.Fatalf("unknown import policy %q, e.g.: -imports skip", *importPolicy)
+	}
+
+	switch *creditPolicy {
+	case vcs.CreditAuthor, vcs.CreditCommitter, vcs.CreditSplit, vcs.CreditShared:
+		vcs.CreditPolicy = *creditPolicy
+	default:
+		log.Fatalf("unknown credit policy %q, e.g.: -credit split", *creditPolicy)
+	}
+
+	vcs.DetectGenerated = *generated
+	vcs.KeepHunks = *keepHunks || *ownership
+	vcs.Workers = *workers
+	vcs.Blobs.MaxSize = *cacheSize << 20
+	vcs.DiskCache = *diskCache
+	vcs.WorkspaceRoot = *workspaceRoot
+	vcs.SnapshotFile = *snapshotFile
+
+	if *listWorkspaces || *pruneAge > 0 {
+		manageWorkspaces()
+		return
+	}
+
+	if *pathConfig != "" {
+		if err := vcs.Paths.LoadConfig(*pathConfig); err != nil {
+			log.Fatal(err)
+		}
+	}
+	vcs.Paths.Include = append(vcs.Paths.Include, splitList(*includePaths)...)
+	vcs.Paths.Exclude = append(vcs.Paths.Exclude, splitList(*excludePaths)...)
 
 	// load repo
 	if *repoPath == "" {
@@ -53,12 +136,51 @@ func main() {
 		log.Fatal(err)
 	}
 
-	//TODO path validation?
-	if *outputFilename == "" {
-		outputFile, _ = os.Create(path.Join(repo.Workspace, "result.xml"))
-	} else {
-		outputFile, _ = os.Create(*outputFilename)
+	//the default output file is only kept within the workspace, if the workspace is kept
+	if *outputFilename == "" && *keepWorkspace {
+		*outputFilename = path.Join(repo.Workspace, "result.xml")
+	} else if *outputFilename == "" {
+		*outputFilename = "result.xml"
 	}
+	if outputFile, err = os.Create(*outputFilename); err != nil {
+		log.Fatal(err)
+	}
+	defer outputFile.Close()
+
+	if err := analyzer.RestoreSnapshot(repo); err != nil {
+		log.Printf("unable to restore analyzer snapshot: %s", err)
+	}
+
+	export.SaveRepositoryInfo(repo)
+	submoduleResults := submoduleResultFiles(repo, outputFile.Name())
+	export.SaveSubmodules(repo.Submodules, submoduleResults)
+
+	executeAnalyses()
+
+	log.Printf("saved results to %s", outputFile.Name())
+	export.SaveFile(outputFile)
+
+	if *snapshotFile != "" {
+		if err := analyzer.UpdateSnapshot(repo); err != nil {
+			log.Fatal(err)
+		}
+		if err := repo.SaveSnapshot(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	analyzeSubmodules(repo, submoduleResults)
+
+	//incremental runs continue with the clone of the workspace
+	if !*keepWorkspace && *snapshotFile == "" {
+		if err := repo.Cleanup(); err != nil {
+			log.Printf("unable to delete workspace: %s", err)
+		}
+	}
+}
+
+//executes the selected analyses for the current repository
+func executeAnalyses() {
 
 	runStyleClassification = true
 	runContributionClassification = true
@@ -67,15 +189,92 @@ func main() {
 		executeCompleteClassification()
 	}
 
-	if *metrics {
+	if *metrics && contentsAvailable("metrics calculation") {
 		executeMetricsCalculation()
 	}
 
-	log.Printf("saved results to %s", outputFile.Name())
-	export.SaveFile(outputFile)
+	if *releases && contentsAvailable("release metrics calculation") {
+		executeReleaseMetricsCalculation()
+	}
 
-	//TODO clean up (delete workspace, ...)
+	if *ownership && contentsAvailable("ownership calculation") {
+		executeOwnershipCalculation()
+	}
+}
 
+//returns the result files of the loaded submodules by path, e.g. "result.vendor_lib.xml"
+func submoduleResultFiles(superproject *vcs.Repository, filename string) map[string]string {
+	ext := path.Ext(filename)
+	results := map[string]string{}
+	for _, submodule := range superproject.Submodules {
+		if submodule.Repository != nil {
+			name := strings.Replace(submodule.Path, "/", "_", -1)
+			results[submodule.Path] = strings.TrimSuffix(filename, ext) + "." + name + ext
+		}
+	}
+	return results
+}
+
+//analyzes the loaded submodules (recursively) as separate repositories, each with its own result file
+func analyzeSubmodules(superproject *vcs.Repository, results map[string]string) {
+
+	//the path rules of the superproject do not apply to its submodules (like while loading them)
+	paths := vcs.Paths
+	defer func() {
+		repo, vcs.Paths = superproject, paths
+	}()
+
+	for _, submodule := range superproject.Submodules {
+		filename, exists := results[submodule.Path]
+		if exists == false {
+			continue
+		}
+
+		log.Printf("started analysis of submodule %s", submodule.Path)
+		repo, vcs.Paths = submodule.Repository, &vcs.PathFilter{}
+		export.Reset()
+		analyzer.ResetHistory()
+
+		export.SaveRepositoryInfo(repo)
+		submoduleResults := submoduleResultFiles(repo, filename)
+		export.SaveSubmodules(repo.Submodules, submoduleResults)
+
+		executeAnalyses()
+
+		file, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("saved results of submodule %s to %s", submodule.Path, file.Name())
+		export.SaveFile(file)
+		file.Close()
+
+		analyzeSubmodules(repo, submoduleResults)
+	}
+}
+
+//lists or prunes the workspaces of previous runs
+func manageWorkspaces() {
+
+	if *pruneAge > 0 {
+		pruned, err := vcs.PruneWorkspaces(*pruneAge)
+		for _, workspace := range pruned {
+			fmt.Printf("deleted %s\n", workspace)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *listWorkspaces {
+		workspaces, err := vcs.ListWorkspaces()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, workspace := range workspaces {
+			fmt.Println(workspace)
+		}
+	}
 }
 
 func executeMetricsCalculation() {
@@ -104,11 +303,54 @@ func executeMetricsCalculation() {
 	export.SaveFunctions(analyzer.History)
 }
 
+func executeReleaseMetricsCalculation() {
+	log.Println("started release metric extraction")
+
+	metrics := analyzer.CalcReleaseMetrics(repo)
+	for _, crt := range metrics {
+		log.Printf("\t release %s: stability %.2f, style homogeneity %.2f, contribution homogeneity %.2f",
+			crt.Release.Name(), crt.Stability, crt.StyleHomogeneity, crt.ContributionHomogeneity)
+	}
+
+	export.SaveReleaseMetrics(metrics)
+}
+
+func executeOwnershipCalculation() {
+	log.Println("started ownership calculation")
+
+	head := repo.Head()
+	if head == nil {
+		return
+	}
+
+	ownerships, err := analyzer.FileOwnership(repo, head)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if project, exists := analyzer.DirectoryOwnership(ownerships)["."]; exists {
+		owner, share := project.PrimaryOwner()
+		log.Printf("\t primary owner: %s (%.2f), fragmentation: %.2f", owner, share, project.Fragmentation())
+	}
+
+	export.SaveOwnership(ownerships)
+}
+
 func executeCompleteClassification() {
-	executeStyleClassification()
+	if contentsAvailable("style classification") {
+		executeStyleClassification()
+	}
 	executeContributionClassification()
 }
 
+//checks if the file contents needed by an analysis are available (e.g. not for imported logs)
+func contentsAvailable(analysis string) bool {
+	if repo.HasContents() == false {
+		log.Printf("%s is disabled, file contents are not available for %s repositories", analysis, repo.SystemName())
+		return false
+	}
+	return true
+}
+
 func executeStyleClassification() {
 	log.Println("started style classification")
 	styleRawMatrix := analyzer.StyleData(repo)
@@ -124,3 +366,34 @@ func executeContributionClassification() {
 	export.SaveClassificationResult("contribution", contributionGroups, contributionRawMatrix)
 	runContributionClassification = false
 }
+
+//parses a date (YYYY-MM-DD or RFC 3339), a date without time marks the start or end of the day
+func parseDate(value string, endOfDay bool) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		log.Fatalf("invalid date %q, e.g.: 2015-01-31", value)
+	}
+	if endOfDay {
+		date = date.Add(24*time.Hour - time.Nanosecond)
+	}
+	return date
+}
+
+//splits a comma separated list of values
+func splitList(value string) []string {
+	values := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
@@ -0,0 +1,126 @@
+package vcs
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+//trailer of a commit message naming an additional author, e.g. "Co-authored-by: Jane Doe <jane@example.org>"
+var coAuthorPattern = regexp.MustCompile(`(?im)^\s*co-authored-by:\s*([^<\r\n]*?)\s*<([^>\r\n]*)>\s*$`)
+
+//returns the developer of an identity, it is created if it is not known yet
+func developerFor(developers map[string]*Developer, id string, email string, name string) *Developer {
+	dev, exists := developers[id]
+	if !exists {
+		dev = NewDeveloper(id, email, name)
+		developers[id] = dev
+	}
+	return dev
+}
+
+//sets the developer who committed (or applied) the changes of the commit
+func (c *Commit) SetCommitter(dev *Developer) {
+	if c.Committer != nil {
+		delete(c.Committer.Committed, c.Id)
+	}
+	c.Committer = dev
+	dev.Committed[c.Id] = c
+}
+
+//adds a developer who authored the changes together with the author of the commit
+func (c *Commit) AddCoAuthor(dev *Developer) {
+	if dev == c.Developer {
+		return
+	}
+	for _, coAuthor := range c.CoAuthors {
+		if coAuthor == dev {
+			return
+		}
+	}
+	c.CoAuthors = append(c.CoAuthors, dev)
+	dev.CoAuthored[c.Id] = c
+}
+
+//adds the co-authors named by the trailers of the commit message, developers are identified by their email
+func (c *Commit) readCoAuthors(developers map[string]*Developer) {
+	for _, match := range coAuthorPattern.FindAllStringSubmatch(c.Message, -1) {
+		name, email := match[1], strings.TrimSpace(match[2])
+		if email == "" {
+			continue
+		}
+		c.AddCoAuthor(developerFor(developers, email, email, name))
+	}
+}
+
+//returns the author and all co-authors of the commit
+func (c *Commit) Authors() []*Developer {
+	return append([]*Developer{c.Developer}, c.CoAuthors...)
+}
+
+//returns the share of the commit credited to a developer (between 0 and 1) by the credit policy
+func (c *Commit) Credit(dev *Developer) float64 {
+
+	switch CreditPolicy {
+	case CreditCommitter:
+		committer := c.Committer
+		if committer == nil {
+			committer = c.Developer
+		}
+		if committer == dev {
+			return 1.0
+		}
+	case CreditSplit, CreditShared:
+		authors := c.Authors()
+		for _, author := range authors {
+			if author != dev {
+				continue
+			}
+			if CreditPolicy == CreditSplit {
+				return 1.0 / float64(len(authors))
+			}
+			return 1.0
+		}
+	default:
+		if c.Developer == dev {
+			return 1.0
+		}
+	}
+	return 0.0
+}
+
+//returns all commits credited to the developer with the credited share of each commit
+func (dev *Developer) Credits() map[*Commit]float64 {
+
+	credits := map[*Commit]float64{}
+	for _, commits := range []map[string]*Commit{dev.Commits, dev.Committed, dev.CoAuthored} {
+		for _, commit := range commits {
+			if share := commit.Credit(dev); share > 0 {
+				credits[commit] = share
+			}
+		}
+	}
+	return credits
+}
+
+/*
+checks if the developer authored no commit and gets no share of other commits by
+the credit policy (committers and co-authors are only developers of the repository,
+if they are credited)
+*/
+func (dev *Developer) isEmpty() bool {
+	if len(dev.Commits) > 0 {
+		return false
+	}
+	switch CreditPolicy {
+	case CreditCommitter:
+		return len(dev.Committed) == 0
+	case CreditSplit, CreditShared:
+		return len(dev.CoAuthored) == 0
+	}
+	return true
+}
+
+func roundCredit(value float64) int {
+	return int(math.Floor(value + 0.5))
+}
@@ -0,0 +1,213 @@
+package vcs
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+)
+
+var ErrNoHunks = errors.New("blame needs the hunks of all commits (KeepHunks)")
+
+//last change of a line
+type BlameLine struct {
+	//commit which added the line in its current form
+	Commit *Commit
+	//path and line number of the line within this commit
+	Path    string
+	Line    int
+	Content string
+}
+
+//line of a blamed file which is not assigned to a commit yet
+type blameTarget struct {
+	path  string
+	index int
+	line  int
+}
+
+/*
+returns the files of a revision by replaying the changes of its first parent
+chain, for a selected window only files changed within the window are known
+*/
+func (r *Repository) FilesAt(revision *Commit) map[string]*File {
+
+	files := map[string]*File{}
+	for _, commit := range r.FirstParentChain(revision) {
+		if len(commit.Diffs) == 0 {
+			continue
+		}
+		diff := commit.Diffs[0]
+
+		for oldPath, path := range diff.MovedFiles {
+			if file, exists := files[oldPath]; exists {
+				files[path] = file
+				delete(files, oldPath)
+			}
+		}
+		for path := range diff.RemovedFiles {
+			delete(files, path)
+		}
+		for path, file := range diff.ChangedFiles {
+			files[path] = file
+		}
+		for path, file := range diff.AddedFiles {
+			files[path] = file
+		}
+		for path, file := range diff.ExistingFiles {
+			files[path] = file
+		}
+	}
+	return files
+}
+
+//returns the last change of every line of a file at the given revision
+func (r *Repository) Blame(revision *Commit, path string) ([]BlameLine, error) {
+	blames, err := r.BlameFiles(revision, []string{path})
+	if err != nil {
+		return nil, err
+	}
+	return blames[path], nil
+}
+
+/*
+returns the last change of every line for several files of a revision within a
+single pass over the history: the lines are passed from child to parent as long
+as they are unchanged (following renames), a line is blamed on the commit which
+added it compared to all of its parents, lines older than the selected window
+(or the boundary of a shallow clone) are blamed on the oldest commit of the
+window, files with missing content are skipped
+*/
+func (r *Repository) BlameFiles(revision *Commit, paths []string) (map[string][]BlameLine, error) {
+
+	if KeepHunks == false {
+		return nil, ErrNoHunks
+	}
+
+	files := r.FilesAt(revision)
+	blames := map[string][]BlameLine{}
+
+	//lines which are not assigned yet, by commit and path within the commit
+	pending := map[string]map[string][]blameTarget{}
+	pending[revision.Id] = map[string][]blameTarget{}
+
+	for _, path := range paths {
+		file, exists := files[path]
+		if exists == false {
+			continue
+		}
+
+		content, err := file.Content()
+		if err != nil {
+			log.Printf("unable to blame %s: %s", path, err)
+			continue
+		}
+
+		lines := splitLines(content)
+		blames[path] = make([]BlameLine, len(lines))
+		for n, content := range lines {
+			blames[path][n].Content = content
+			pending[revision.Id][path] = append(pending[revision.Id][path], blameTarget{path, n, n + 1})
+		}
+	}
+
+	//children are handled before their parents
+	commits := r.TopologicalOrder()
+	for i := len(commits) - 1; i >= 0 && len(pending) > 0; i-- {
+		commit := commits[i]
+		targets, exists := pending[commit.Id]
+		if exists == false {
+			continue
+		}
+		delete(pending, commit.Id)
+
+		for path, lines := range targets {
+			for _, target := range passToParents(commit, path, lines, pending) {
+				blames[target.path][target.index].Commit = commit
+				blames[target.path][target.index].Path = path
+				blames[target.path][target.index].Line = target.line
+			}
+		}
+	}
+
+	return blames, nil
+}
+
+//passes unchanged lines of a file to the first parent they exist in, returns the lines added by the commit
+func passToParents(commit *Commit, path string, lines []blameTarget, pending map[string]map[string][]blameTarget) []blameTarget {
+
+	for _, diff := range commit.Diffs {
+		if len(lines) == 0 {
+			break
+		}
+		if _, added := diff.AddedFiles[path]; added {
+			continue
+		}
+
+		//parents outside of the selected window are not followed
+		parent, exists := commit.Parents[diff.Parent]
+		if exists == false {
+			continue
+		}
+
+		oldPath := path
+		for from, to := range diff.MovedFiles {
+			if to == path {
+				oldPath = from
+			}
+		}
+
+		remaining := []blameTarget{}
+		for _, target := range lines {
+			if oldLine, unchanged := mapLine(diff.Hunks[path], target.line); unchanged {
+				if pending[parent.Id] == nil {
+					pending[parent.Id] = map[string][]blameTarget{}
+				}
+				target.line = oldLine
+				pending[parent.Id][oldPath] = append(pending[parent.Id][oldPath], target)
+			} else {
+				remaining = append(remaining, target)
+			}
+		}
+		lines = remaining
+	}
+
+	return lines
+}
+
+//returns the line number within the old version of a file, false if the line was added
+func mapLine(hunks []*Hunk, newLine int) (int, bool) {
+
+	sorted := make([]*Hunk, len(hunks))
+	copy(sorted, hunks)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].NewStart < sorted[j].NewStart })
+
+	offset := 0
+	for _, hunk := range sorted {
+		first, end := hunk.NewStart, hunk.NewStart+hunk.NewLines
+		if hunk.NewLines == 0 {
+			first, end = hunk.NewStart+1, hunk.NewStart+1
+		}
+
+		if newLine < first {
+			break
+		}
+		if newLine < end {
+			for _, line := range hunk.Lines {
+				if line.NewLine == newLine {
+					return line.OldLine, line.Origin == LineContext
+				}
+			}
+		}
+		offset += hunk.NewLines - hunk.OldLines
+	}
+	return newLine - offset, true
+}
+
+//splits the content of a file into lines (without line breaks)
+func splitLines(content string) []string {
+	if content == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
@@ -0,0 +1,60 @@
+package vcs
+
+import "testing"
+
+/*
+a line is changed on the main branch, another one is added on a feature branch
+and the merge adds a line itself, at last the file is renamed and extended
+*/
+func TestBlameFiles(t *testing.T) {
+
+	Filter = PassThroughFilter{}
+	KeepHunks = true
+	defer func() { KeepHunks = false }()
+
+	b := NewBuilder()
+	root := b.Commit(CommitSpec{Author: "alice@example.com", Date: testDate(1), Message: "add a",
+		Write: map[string]string{"a.txt": "one\ntwo\nthree\n"}})
+	main := b.Commit(CommitSpec{Author: "bob@example.com", Date: testDate(2), Message: "change two",
+		Parents: []*Commit{root}, Write: map[string]string{"a.txt": "one\nTWO\nthree\n"}})
+	feature := b.Commit(CommitSpec{Author: "carol@example.com", Date: testDate(3), Message: "add four",
+		Parents: []*Commit{root}, Write: map[string]string{"a.txt": "one\ntwo\nthree\nfour\n"}})
+	merge := b.Commit(CommitSpec{Author: "alice@example.com", Date: testDate(4), Message: "merge feature",
+		Parents: []*Commit{main, feature}, Write: map[string]string{"a.txt": "one\nTWO\nthree\nfour\nmerged\n"}})
+	rename := b.Commit(CommitSpec{Author: "dave@example.com", Date: testDate(5), Message: "rename a, add five",
+		Parents: []*Commit{merge}, Rename: map[string]string{"a.txt": "b.txt"}, Write: map[string]string{"b.txt": "one\nTWO\nthree\nfour\nmerged\nfive\n"}})
+
+	expected := []struct {
+		content string
+		commit  *Commit
+		path    string
+		line    int
+	}{
+		{"one", root, "a.txt", 1},
+		{"TWO", main, "a.txt", 2},
+		{"three", root, "a.txt", 3},
+		{"four", feature, "a.txt", 4},
+		{"merged", merge, "a.txt", 5},
+		{"five", rename, "b.txt", 6},
+	}
+
+	repo := b.Repository()
+	lines, err := repo.Blame(rename, "b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %v", len(expected), lines)
+	}
+	for n, line := range lines {
+		if line.Content != expected[n].content || line.Commit != expected[n].commit || line.Path != expected[n].path || line.Line != expected[n].line {
+			t.Errorf("expected %q from %q (%s:%d), got %q from %v (%s:%d)", expected[n].content, expected[n].commit.Message,
+				expected[n].path, expected[n].line, line.Content, line.Commit, line.Path, line.Line)
+		}
+	}
+
+	KeepHunks = false
+	if _, err := repo.BlameFiles(rename, []string{"b.txt"}); err != ErrNoHunks {
+		t.Errorf("expected ErrNoHunks without hunks, got %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package vcs
+
+import (
+	"crypto/sha1"
+	"fmt"
+	linediff "github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+builds a repository in memory (e.g. for tests or tools without a vcs): commits
+are scripted by their changes, the diffs to every parent are computed like the
+connectors do (renames have to be declared, they are followed to the other parents
+of merges), contents of files are kept in
+memory, paths are checked by ValidPath if a language filter is set
+*/
+type Builder struct {
+	repo *Repository
+	//files of every commit by path
+	trees    map[string]map[string]*File
+	contents map[string][]byte
+}
+
+//commit to build, all changes are relative to the first parent
+type CommitSpec struct {
+	//generated if empty
+	Id string
+	//"Name <email>" or only an email, the committer is the author if empty
+	Author    string
+	Committer string
+	Date      time.Time
+	Message   string
+	//first parent first, a root commit if empty
+	Parents []*Commit
+
+	//contents of added or changed files by path
+	Write map[string]string
+	//paths of removed files
+	Remove []string
+	//renamed files (old path to new path), the content is kept unless it is changed by Write
+	Rename map[string]string
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		repo: &Repository{
+			Commits:    map[string]*Commit{},
+			Developers: map[string]*Developer{},
+			Tags:       []*Tag{},
+			Skipped:    []error{},
+			Excluded:   map[string]string{},
+			System:     MEMORY,
+		},
+		trees:    map[string]map[string]*File{},
+		contents: map[string][]byte{},
+	}
+}
+
+//returns the built repository, further commits are added to it
+func (b *Builder) Repository() *Repository {
+	return b.repo
+}
+
+//adds a tag for a commit, the date of the tag is the date of the commit
+func (b *Builder) Tag(name string, commit *Commit) *Tag {
+	tag := &Tag{name, commit, commit.Date}
+	b.repo.Tags = append(b.repo.Tags, tag)
+	sort.Sort(tagsByDate(b.repo.Tags))
+	return tag
+}
+
+//returns the files of a built commit by path
+func (b *Builder) FilesAt(commit *Commit) map[string]*File {
+	files := map[string]*File{}
+	for path, file := range b.trees[commit.Id] {
+		files[path] = file
+	}
+	return files
+}
+
+//creates a commit with its changes to every parent and links it to its parents
+func (b *Builder) Commit(spec CommitSpec) *Commit {
+
+	id := spec.Id
+	if id == "" {
+		h := sha1.New()
+		fmt.Fprintf(h, "commit %d\x00%s", len(b.repo.Commits), spec.Message)
+		id = fmt.Sprintf("%x", h.Sum(nil))
+	}
+
+	name, email := parseGitLogPerson(spec.Author)
+	dev := developerFor(b.repo.Developers, email, email, name)
+	commit := NewCommit(id, spec.Message, spec.Date, dev)
+	b.repo.Commits[id] = commit
+	dev.Commits[id] = commit
+
+	if spec.Committer != "" {
+		name, email := parseGitLogPerson(spec.Committer)
+		commit.SetCommitter(developerFor(b.repo.Developers, email, email, name))
+	} else {
+		commit.SetCommitter(dev)
+	}
+	commit.readCoAuthors(b.repo.Developers)
+
+	//the tree of the commit is based on the tree of the first parent
+	tree := map[string]*File{}
+	if len(spec.Parents) > 0 {
+		for path, file := range b.trees[spec.Parents[0].Id] {
+			tree[path] = file
+		}
+	}
+	for oldPath, path := range spec.Rename {
+		if file, exists := tree[oldPath]; exists {
+			tree[path] = file
+			delete(tree, oldPath)
+		}
+	}
+	for _, path := range spec.Remove {
+		delete(tree, path)
+	}
+	for path, content := range spec.Write {
+		tree[path] = b.file(content)
+	}
+	b.trees[id] = tree
+
+	if len(spec.Parents) == 0 {
+		b.addDiff(commit, "", map[string]*File{}, tree, spec.Rename)
+	}
+	for _, parent := range spec.Parents {
+		commit.Parents[parent.Id] = parent
+		parent.Children[id] = commit
+		b.addDiff(commit, parent.Id, b.trees[parent.Id], tree, spec.Rename)
+	}
+
+	return commit
+}
+
+//creates the file object of a content, the content is kept by the builder
+func (b *Builder) file(content string) *File {
+	id := blobId([]byte(content))
+	b.contents[id] = []byte(content)
+	return newFile(id, int64(len(content)), "", b.fileLoader(id, ""))
+}
+
+//contents are loaded by their blob id
+func (b *Builder) fileLoader(id string, source string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		content, exists := b.contents[id]
+		if exists == false {
+			return nil, &BlobError{id, ErrBlobMissing}
+		}
+		return content, nil
+	}
+}
+
+//adds the changes between the tree of a parent (empty for root commits) and the tree of the commit
+func (b *Builder) addDiff(commit *Commit, parentId string, parentTree map[string]*File, tree map[string]*File, renames map[string]string) {
+
+	diff := NewParentDiff(parentId)
+	moved := map[string]bool{}
+
+	//files renamed by earlier commits (e.g. compared to the other parents of a merge) are moved as well
+	renames = b.followRenames(parentTree, tree, renames)
+
+	for oldPath, path := range renames {
+		oldFile, exists := parentTree[oldPath]
+		file, renamed := tree[path]
+		if exists == false || renamed == false || b.validPath(path) == false {
+			continue
+		}
+		if _, kept := tree[oldPath]; kept {
+			continue
+		}
+		if _, replaced := parentTree[path]; replaced {
+			continue
+		}
+
+		//moved files are part of the commit like with the connectors, even if only their path changed
+		moved[oldPath], moved[path] = true, true
+		diff.MovedFiles[oldPath] = path
+		commit.Files[path] = file
+		if file.Id != oldFile.Id {
+			diff.ChangedFiles[path] = file
+			addFileParent(file, oldFile)
+		}
+		b.addLines(diff, path, oldPath, oldFile, file)
+	}
+
+	for path, file := range tree {
+		if moved[path] || b.validPath(path) == false {
+			continue
+		}
+		oldFile, exists := parentTree[path]
+		switch {
+		case exists == false:
+			diff.AddedFiles[path] = file
+		case oldFile.Id != file.Id:
+			diff.ChangedFiles[path] = file
+			addFileParent(file, oldFile)
+		default:
+			continue
+		}
+		commit.Files[path] = file
+		if exists {
+			b.addLines(diff, path, path, oldFile, file)
+		} else {
+			b.addLines(diff, path, "", nil, file)
+		}
+	}
+
+	for path, oldFile := range parentTree {
+		if _, exists := tree[path]; exists || moved[path] || b.validPath(path) == false {
+			continue
+		}
+		diff.RemovedFiles[path] = oldFile
+		b.addLines(diff, path, path, oldFile, nil)
+	}
+
+	commit.AddDiff(diff)
+}
+
+//adds the renames of files which were removed from the parent tree and descend from a file added to the tree
+func (b *Builder) followRenames(parentTree map[string]*File, tree map[string]*File, renames map[string]string) map[string]string {
+
+	followed := map[string]string{}
+	for oldPath, path := range renames {
+		followed[oldPath] = path
+	}
+
+	for path, file := range tree {
+		if _, exists := parentTree[path]; exists {
+			continue
+		}
+		for oldPath, oldFile := range parentTree {
+			if _, kept := tree[oldPath]; kept {
+				continue
+			}
+			if _, renamed := followed[oldPath]; renamed == false && descendsFrom(file, oldFile) {
+				followed[oldPath] = path
+				break
+			}
+		}
+	}
+	return followed
+}
+
+//checks if a file is a later version of another file
+func descendsFrom(file *File, ancestor *File) bool {
+	if file == ancestor {
+		return true
+	}
+	for _, parent := range file.Parents {
+		if descendsFrom(parent, ancestor) {
+			return true
+		}
+	}
+	return false
+}
+
+//adds the previous version of a file once (merges may compare it to the same version twice)
+func addFileParent(file *File, parent *File) {
+	for _, existing := range file.Parents {
+		if existing == parent {
+			return
+		}
+	}
+	file.Parents = append(file.Parents, parent)
+}
+
+//counts the changed lines of a file (and keeps its hunks, if KeepHunks is set)
+func (b *Builder) addLines(diff *ParentDiff, path string, oldPath string, oldFile *File, file *File) {
+
+	var oldContent, content string
+	if oldFile != nil {
+		oldContent = string(b.contents[oldFile.Id])
+	}
+	if file != nil {
+		content = string(b.contents[file.Id])
+	}
+
+	lines := []HunkLine{}
+	lineDiff := LineDiff{}
+	for _, chunk := range linediff.Do(oldContent, content) {
+		var origin byte = LineContext
+		switch chunk.Type {
+		case diffmatchpatch.DiffInsert:
+			origin = LineAdded
+		case diffmatchpatch.DiffDelete:
+			origin = LineRemoved
+		}
+		for _, line := range strings.SplitAfter(chunk.Text, "\n") {
+			if line == "" {
+				continue
+			}
+			lines = append(lines, HunkLine{Origin: origin, Content: line})
+			switch origin {
+			case LineAdded:
+				lineDiff.Added++
+			case LineRemoved:
+				lineDiff.Removed++
+			}
+		}
+	}
+
+	diff.AddLines(path, lineDiff)
+	if KeepHunks {
+		for _, hunk := range splitHunks(path, oldPath, lines) {
+			diff.AddHunk(hunk)
+		}
+	}
+}
+
+//all paths are valid without a language filter
+func (b *Builder) validPath(path string) bool {
+	return Filter == nil || ValidPath(path)
+}
@@ -0,0 +1,103 @@
+package vcs
+
+import (
+	"testing"
+	"time"
+)
+
+//returns the date of the n-th test commit
+func testDate(n int) time.Time {
+	return time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+func TestBuilderRename(t *testing.T) {
+
+	b := NewBuilder()
+	add := b.Commit(CommitSpec{Author: "Alice <alice@example.com>", Date: testDate(1), Message: "add a",
+		Write: map[string]string{"a.txt": "one\ntwo\n"}})
+	move := b.Commit(CommitSpec{Author: "Alice <alice@example.com>", Date: testDate(2), Message: "move a",
+		Parents: []*Commit{add}, Rename: map[string]string{"a.txt": "b.txt"}})
+	change := b.Commit(CommitSpec{Author: "Bob <bob@example.com>", Date: testDate(3), Message: "move and change b",
+		Parents: []*Commit{move}, Rename: map[string]string{"b.txt": "c.txt"}, Write: map[string]string{"c.txt": "one\ntwo\nthree\n"}})
+
+	if len(add.AddedFiles) != 1 || add.LineDiff != (LineDiff{2, 0}) {
+		t.Errorf("expected a.txt with 2 lines to be added, got %v %v", add.AddedFiles, add.LineDiff)
+	}
+
+	if move.MovedFiles["a.txt"] != "b.txt" || len(move.ChangedFiles) > 0 || len(move.AddedFiles) > 0 || len(move.RemovedFiles) > 0 {
+		t.Errorf("expected only a move of a.txt, got %v %v %v %v", move.MovedFiles, move.ChangedFiles, move.AddedFiles, move.RemovedFiles)
+	}
+	if move.LineDiff != (LineDiff{}) {
+		t.Errorf("expected no changed lines for a move, got %v", move.LineDiff)
+	}
+
+	file := change.ChangedFiles["c.txt"]
+	if change.MovedFiles["b.txt"] != "c.txt" || file == nil || len(change.AddedFiles) > 0 || len(change.RemovedFiles) > 0 {
+		t.Fatalf("expected b.txt to be moved and changed, got %v %v", change.MovedFiles, change.ChangedFiles)
+	}
+	if change.LineDiff != (LineDiff{1, 0}) {
+		t.Errorf("expected 1 added line, got %v", change.LineDiff)
+	}
+	if len(file.Parents) != 1 || file.Parents[0] != add.Files["a.txt"] {
+		t.Errorf("expected the added version as parent of c.txt, got %v", file.Parents)
+	}
+
+	if content, err := file.Content(); err != nil || content != "one\ntwo\nthree\n" {
+		t.Errorf("unexpected content of c.txt %q (%v)", content, err)
+	}
+	if content, err := file.Parents[0].Content(); err != nil || content != "one\ntwo\n" {
+		t.Errorf("unexpected content of a.txt %q (%v)", content, err)
+	}
+	if files := b.FilesAt(change); len(files) != 1 || files["c.txt"] != file {
+		t.Errorf("expected only c.txt at the last commit, got %v", files)
+	}
+}
+
+/*
+merges a branch which renamed a file twice: once with the rename declared for
+the merge and once with the merged branch as first parent, so the rename has to
+be followed to the other parent
+*/
+func TestBuilderMerge(t *testing.T) {
+
+	b := NewBuilder()
+	root := b.Commit(CommitSpec{Author: "alice@example.com", Date: testDate(1), Message: "add a",
+		Write: map[string]string{"a.txt": "a\n"}})
+	main := b.Commit(CommitSpec{Author: "alice@example.com", Date: testDate(2), Message: "add b",
+		Parents: []*Commit{root}, Write: map[string]string{"b.txt": "b\n"}})
+	branch := b.Commit(CommitSpec{Author: "bob@example.com", Date: testDate(3), Message: "move a",
+		Parents: []*Commit{root}, Rename: map[string]string{"a.txt": "d.txt"}})
+
+	merge := b.Commit(CommitSpec{Author: "alice@example.com", Date: testDate(4), Message: "merge branch",
+		Parents: []*Commit{main, branch}, Rename: map[string]string{"a.txt": "d.txt"}})
+	reversed := b.Commit(CommitSpec{Author: "bob@example.com", Date: testDate(5), Message: "merge main",
+		Parents: []*Commit{branch, main}, Write: map[string]string{"b.txt": "b\n"}})
+
+	for _, commit := range []*Commit{merge, reversed} {
+		if len(commit.Parents) != 2 || len(commit.Diffs) != 2 {
+			t.Fatalf("expected 2 parents and diffs for %q, got %d and %d", commit.Message, len(commit.Parents), len(commit.Diffs))
+		}
+		diffs := map[string]*ParentDiff{}
+		for _, diff := range commit.Diffs {
+			diffs[diff.Parent] = diff
+		}
+
+		toMain, toBranch := diffs[main.Id], diffs[branch.Id]
+		if toMain.MovedFiles["a.txt"] != "d.txt" || len(toMain.AddedFiles) > 0 || len(toMain.RemovedFiles) > 0 || toMain.LineDiff != (LineDiff{}) {
+			t.Errorf("expected only a move compared to %q, got %v %v %v", commit.Message, toMain.MovedFiles, toMain.AddedFiles, toMain.RemovedFiles)
+		}
+		if len(toBranch.AddedFiles) != 1 || toBranch.AddedFiles["b.txt"] == nil || len(toBranch.MovedFiles) > 0 || toBranch.LineDiff != (LineDiff{1, 0}) {
+			t.Errorf("expected b.txt to be added compared to %q, got %v %v", commit.Message, toBranch.AddedFiles, toBranch.MovedFiles)
+		}
+		if main.Children[commit.Id] != commit || branch.Children[commit.Id] != commit {
+			t.Errorf("%q is not linked to its parents", commit.Message)
+		}
+	}
+
+	if order := merge.OrderedParents(); order[0] != main || order[1] != branch {
+		t.Errorf("expected the first parent first, got %v", order)
+	}
+	if heads := b.Repository().Heads(); len(heads) != 2 {
+		t.Errorf("expected both merges as heads, got %v", heads)
+	}
+}
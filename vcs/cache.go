@@ -0,0 +1,173 @@
+package vcs
+
+import (
+	"compress/gzip"
+	"container/list"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+//cached content of a single blob
+type cacheEntry struct {
+	id      string
+	content []byte
+}
+
+/*
+content addressed cache for file contents, blobs are kept in memory up to
+the maximum size (least recently used blobs are dropped first), optionally
+blobs are stored compressed within a directory
+*/
+type BlobCache struct {
+	MaxSize int64
+	Dir     string
+
+	mutex   sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+func NewBlobCache(maxSize int64) *BlobCache {
+	return &BlobCache{
+		MaxSize: maxSize,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+//returns the content of a blob, the loader is only called if the blob is not cached
+func (cache *BlobCache) Get(id string, load func() ([]byte, error)) ([]byte, error) {
+
+	cache.mutex.Lock()
+	if element, exists := cache.entries[id]; exists {
+		cache.lru.MoveToFront(element)
+		cache.mutex.Unlock()
+		return element.Value.(*cacheEntry).content, nil
+	}
+	cache.mutex.Unlock()
+
+	content, err := cache.readDisk(id)
+	if err != nil {
+		if content, err = load(); err != nil {
+			return nil, err
+		}
+		cache.writeDisk(id, content)
+	}
+
+	cache.add(id, content)
+	return content, nil
+}
+
+//returns the content of a blob only if it is cached in memory
+func (cache *BlobCache) cached(id string) ([]byte, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if element, exists := cache.entries[id]; exists {
+		return element.Value.(*cacheEntry).content, true
+	}
+	return nil, false
+}
+
+//adds an already loaded blob to the cache
+func (cache *BlobCache) Put(id string, content []byte) {
+	cache.writeDisk(id, content)
+	cache.add(id, content)
+}
+
+func (cache *BlobCache) add(id string, content []byte) {
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if _, exists := cache.entries[id]; exists {
+		return
+	}
+
+	cache.entries[id] = cache.lru.PushFront(&cacheEntry{id, content})
+	cache.size += int64(len(content))
+
+	//drop least recently used blobs
+	for cache.size > cache.MaxSize && cache.lru.Len() > 1 {
+		element := cache.lru.Back()
+		entry := element.Value.(*cacheEntry)
+		cache.lru.Remove(element)
+		delete(cache.entries, entry.id)
+		cache.size -= int64(len(entry.content))
+	}
+}
+
+//path of a blob within the disk cache (distributed over subdirectories like in git)
+func (cache *BlobCache) diskPath(id string) string {
+	if len(id) < 3 {
+		return filepath.Join(cache.Dir, id+".gz")
+	}
+	return filepath.Join(cache.Dir, id[:2], id[2:]+".gz")
+}
+
+func (cache *BlobCache) readDisk(id string) ([]byte, error) {
+
+	if cache.Dir == "" {
+		return nil, os.ErrNotExist
+	}
+
+	file, err := os.Open(cache.diskPath(id))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+//stores a blob compressed to the disk cache, failures just disable caching of this blob
+func (cache *BlobCache) writeDisk(id string, content []byte) {
+
+	if cache.Dir == "" {
+		return
+	}
+
+	path := cache.diskPath(id)
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+
+	var fm os.FileMode = 0700
+	if err := os.MkdirAll(filepath.Dir(path), fm); err != nil {
+		log.Printf("unable to create cache directory: %s", err)
+		return
+	}
+
+	//write to a temporary file first, so no incomplete blobs are left behind
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), "blob")
+	if err != nil {
+		log.Printf("unable to cache blob %s: %s", id, err)
+		return
+	}
+
+	writer := gzip.NewWriter(tmpFile)
+	_, err = writer.Write(content)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		log.Printf("unable to cache blob %s: %s", id, err)
+	}
+}
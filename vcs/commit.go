@@ -12,6 +12,11 @@ type Commit struct {
 	Message   string
 	Developer *Developer
 
+	//developer who committed the changes of the author (e.g. applied a patch) and
+	//additional authors named by trailers of the message ("Co-authored-by:")
+	Committer *Developer
+	CoAuthors []*Developer
+
 	Files        map[string]*File
 	RemovedFiles map[string]*File
 	ChangedFiles map[string]*File
@@ -20,10 +25,69 @@ type Commit struct {
 
 	LineDiff LineDiff
 
+	//changes compared to each parent (in order of the parents), the maps above contain the changes of all parents
+	Diffs []*ParentDiff
+	//parents of the commit are cut off (e.g. by a shallow clone), its files are not credited as added
+	Boundary bool
+	//prefixes of the parents merged as subtree (e.g. by git subtree) by parent id
+	Subtrees map[string]string
+
 	Parents  map[string]*Commit
 	Children map[string]*Commit
 }
 
+//changes of a commit compared to one of its parents (an empty parent for root commits)
+type ParentDiff struct {
+	Parent       string
+	RemovedFiles map[string]*File
+	ChangedFiles map[string]*File
+	AddedFiles   map[string]*File
+	MovedFiles   map[string]string
+	LineDiff     LineDiff
+	FileLines    map[string]LineDiff
+	//changed lines of each file, only available if KeepHunks is set
+	Hunks map[string][]*Hunk
+	//files of a boundary commit, which already existed within the unknown history,
+	//or files imported by a subtree merge (if imports are skipped)
+	ExistingFiles map[string]*File
+}
+
+func NewParentDiff(parent string) *ParentDiff {
+	return &ParentDiff{
+		Parent:        parent,
+		RemovedFiles:  map[string]*File{},
+		ChangedFiles:  map[string]*File{},
+		AddedFiles:    map[string]*File{},
+		MovedFiles:    map[string]string{},
+		LineDiff:      LineDiff{0, 0},
+		FileLines:     map[string]LineDiff{},
+		Hunks:         map[string][]*Hunk{},
+		ExistingFiles: map[string]*File{},
+	}
+}
+
+/*
+converts the diff of a boundary commit to an empty tree: the added files
+already existed before, so they are neither added nor are their lines counted
+*/
+func (diff *ParentDiff) markExisting() {
+	for path, file := range diff.AddedFiles {
+		diff.ExistingFiles[path] = file
+	}
+	diff.AddedFiles = map[string]*File{}
+	diff.LineDiff = LineDiff{0, 0}
+	diff.FileLines = map[string]LineDiff{}
+	diff.Hunks = map[string][]*Hunk{}
+}
+
+//adds the changed lines of a file
+func (diff *ParentDiff) AddLines(path string, lines LineDiff) {
+	fileLines := diff.FileLines[path]
+	fileLines.Add(lines)
+	diff.FileLines[path] = fileLines
+	diff.LineDiff.Add(lines)
+}
+
 func NewCommit(id string, message string, date time.Time, dev *Developer) *Commit {
 	return &Commit{
 		Id:           id,
@@ -38,7 +102,175 @@ func NewCommit(id string, message string, date time.Time, dev *Developer) *Commi
 		Parents:      map[string]*Commit{},
 		LineDiff:     LineDiff{0, 0},
 		Children:     map[string]*Commit{},
+		Subtrees:     map[string]string{},
+	}
+}
+
+/*
+adds the changes compared to a parent, they are merged into the changes of the
+commit, for boundary commits the files of a diff to the empty tree are existing
+files instead of added ones (as well as the files imported by subtree merges, if
+imports are skipped)
+*/
+func (c *Commit) AddDiff(diff *ParentDiff) {
+	if c.Boundary && diff.Parent == "" {
+		diff.markExisting()
+	}
+	if ImportPolicy != ImportInclude {
+		for _, prefix := range c.Subtrees {
+			diff.markImported(prefix)
+		}
+	}
+	for path, file := range diff.RemovedFiles {
+		c.RemovedFiles[path] = file
+	}
+	for path, file := range diff.ChangedFiles {
+		c.ChangedFiles[path] = file
 	}
+	for path, file := range diff.AddedFiles {
+		c.AddedFiles[path] = file
+	}
+	for oldPath, path := range diff.MovedFiles {
+		c.MovedFiles[oldPath] = path
+	}
+	c.LineDiff.Add(diff.LineDiff)
+	c.Diffs = append(c.Diffs, diff)
+}
+
+func (c *Commit) IsMerge() bool {
+	return len(c.Parents) > 1
+}
+
+/*
+returns the changes credited to the developer of the commit, for merge commits
+the changes are selected by the merge policy (merges are detected by their
+diffs, the parents outside of a selected window are not linked)
+*/
+func (c *Commit) Changes() *ParentDiff {
+
+	if len(c.Diffs) < 2 || MergePolicy == MergeAll {
+		return c.allChanges()
+	}
+
+	switch MergePolicy {
+	case MergeFirstParent:
+		return c.Diffs[0]
+	case MergeConflicts:
+		return c.conflictChanges()
+	}
+
+	//merges are ignored
+	return NewParentDiff("")
+}
+
+//changes compared to all parents
+func (c *Commit) allChanges() *ParentDiff {
+
+	if len(c.Diffs) == 1 {
+		return c.Diffs[0]
+	}
+
+	changes := &ParentDiff{
+		RemovedFiles: c.RemovedFiles,
+		ChangedFiles: c.ChangedFiles,
+		AddedFiles:   c.AddedFiles,
+		MovedFiles:   c.MovedFiles,
+		LineDiff:     c.LineDiff,
+		FileLines:    map[string]LineDiff{},
+		Hunks:        map[string][]*Hunk{},
+	}
+	for _, diff := range c.Diffs {
+		for path, lines := range diff.FileLines {
+			fileLines := changes.FileLines[path]
+			fileLines.Add(lines)
+			changes.FileLines[path] = fileLines
+		}
+		for path, hunks := range diff.Hunks {
+			changes.Hunks[path] = append(changes.Hunks[path], hunks...)
+		}
+	}
+	return changes
+}
+
+/*
+changes of a merge commit which are not part of any parent (conflict resolutions
+or changes of the merge itself): files which differ from all parents, lines are
+the minimum of the changed lines compared to each parent
+*/
+func (c *Commit) conflictChanges() *ParentDiff {
+
+	changes := NewParentDiff("")
+
+	changedInAll := func(path string) bool {
+		for _, diff := range c.Diffs {
+			_, added := diff.AddedFiles[path]
+			_, changed := diff.ChangedFiles[path]
+			if added == false && changed == false {
+				return false
+			}
+		}
+		return true
+	}
+
+	first := c.Diffs[0]
+	for path, file := range first.AddedFiles {
+		if changedInAll(path) {
+			changes.AddedFiles[path] = file
+		}
+	}
+	for path, file := range first.ChangedFiles {
+		if changedInAll(path) {
+			changes.ChangedFiles[path] = file
+		}
+	}
+	for path, file := range first.RemovedFiles {
+		removedInAll := true
+		for _, diff := range c.Diffs[1:] {
+			if _, removed := diff.RemovedFiles[path]; removed == false {
+				removedInAll = false
+			}
+		}
+		if removedInAll {
+			changes.RemovedFiles[path] = file
+		}
+	}
+
+	paths := []string{}
+	for path := range changes.AddedFiles {
+		paths = append(paths, path)
+	}
+	for path := range changes.ChangedFiles {
+		paths = append(paths, path)
+	}
+	for path := range changes.RemovedFiles {
+		paths = append(paths, path)
+	}
+	for _, path := range paths {
+		lines := first.FileLines[path]
+		for _, diff := range c.Diffs[1:] {
+			if crt := diff.FileLines[path]; crt.Added < lines.Added {
+				lines.Added = crt.Added
+			}
+			if crt := diff.FileLines[path]; crt.Removed < lines.Removed {
+				lines.Removed = crt.Removed
+			}
+		}
+		changes.AddLines(path, lines)
+		if hunks, exists := first.Hunks[path]; exists {
+			changes.Hunks[path] = hunks
+		}
+	}
+
+	return changes
+}
+
+/*
+returns the hunks of the credited changes (see Changes) by file, for merge
+commits with the conflicts policy the hunks compared to the first parent are
+returned, no hunks are available unless KeepHunks is set
+*/
+func (c *Commit) Hunks() map[string][]*Hunk {
+	return c.Changes().Hunks
 }
 
 func (c *Commit) String() string {
@@ -10,14 +10,20 @@ type Developer struct {
 	Name    string
 	Email   string
 	Commits map[string]*Commit
+
+	//commits of other authors, which were committed or co-authored by the developer
+	Committed  map[string]*Commit
+	CoAuthored map[string]*Commit
 }
 
 func NewDeveloper(id string, email string, name string) *Developer {
 	return &Developer{
-		Id:      id,
-		Email:   email,
-		Name:    name,
-		Commits: map[string]*Commit{},
+		Id:         id,
+		Email:      email,
+		Name:       name,
+		Commits:    map[string]*Commit{},
+		Committed:  map[string]*Commit{},
+		CoAuthored: map[string]*Commit{},
 	}
 }
 
@@ -37,9 +43,11 @@ func (dev *Developer) FirstCommit() *Commit {
 
 func (dev *Developer) ModifiedFiles() []*File {
 	files := []*File{}
-	for _, commit := range dev.Commits {
-		for _, file := range commit.ChangedFiles {
-			files = append(files, file)
+	for commit := range dev.Credits() {
+		for path, file := range commit.Changes().ChangedFiles {
+			if ValidPath(path) {
+				files = append(files, file)
+			}
 		}
 	}
 	return files
@@ -47,33 +55,39 @@ func (dev *Developer) ModifiedFiles() []*File {
 
 func (dev *Developer) AddedFiles() []*File {
 	files := []*File{}
-	for _, commit := range dev.Commits {
-		for _, file := range commit.AddedFiles {
-			files = append(files, file)
+	for commit := range dev.Credits() {
+		for path, file := range commit.Changes().AddedFiles {
+			if ValidPath(path) {
+				files = append(files, file)
+			}
 		}
 	}
 	return files
 }
 
+//changed lines of all credited commits, weighted by the credited share
 func (dev *Developer) LineDiff() *LineDiff {
 
-	diff := &LineDiff{0, 0}
-	for _, commit := range dev.Commits {
-		diff.Add(commit.LineDiff)
+	added, removed := 0.0, 0.0
+	for commit, share := range dev.Credits() {
+		lines := commit.Changes().LineDiff
+		added += share * float64(lines.Added)
+		removed += share * float64(lines.Removed)
 	}
-	return diff
+	return &LineDiff{roundCredit(added), roundCredit(removed)}
 }
 
+//changed files of all credited commits, weighted by the credited share
 func (dev *Developer) FileDiff() *FileDiff {
 
-	diff := &FileDiff{0, 0, 0}
-	for _, commit := range dev.Commits {
-		diff.Added += len(commit.AddedFiles)
-		diff.Removed += len(commit.RemovedFiles)
-		diff.Changed += len(commit.ChangedFiles)
-		diff.Changed += len(commit.MovedFiles)
+	added, removed, changed := 0.0, 0.0, 0.0
+	for commit, share := range dev.Credits() {
+		changes := commit.Changes()
+		added += share * float64(len(changes.AddedFiles))
+		removed += share * float64(len(changes.RemovedFiles))
+		changed += share * float64(len(changes.ChangedFiles)+len(changes.MovedFiles))
 	}
-	return diff
+	return &FileDiff{roundCredit(added), roundCredit(removed), roundCredit(changed)}
 }
 
 func (dev *Developer) String() string {
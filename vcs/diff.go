@@ -0,0 +1,163 @@
+package vcs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//changes of a single file within an unified diff
+type patchFile struct {
+	OldPath  string
+	NewPath  string
+	LineDiff LineDiff
+	//only collected if KeepHunks is set
+	Hunks []*Hunk
+}
+
+//returns the current path of the file (or the old one if it was deleted)
+func (p *patchFile) Path() string {
+	if p.NewPath == "" {
+		return p.OldPath
+	}
+	return p.NewPath
+}
+
+/*
+parses an unified diff (as created by git, hg or svn) and counts the added and
+removed lines for every file, hunk lines are consumed by the ranges given in
+the hunk header, so lines like "--- foo" within a hunk are handled correctly
+(the hunks themselves are only kept if KeepHunks is set)
+*/
+func parseUnifiedDiff(r io.Reader) ([]*patchFile, error) {
+
+	files := []*patchFile{}
+	var current *patchFile
+	gitStyle := false
+	oldLines, newLines := 0, 0
+	var hunk *Hunk
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		//inside of a hunk
+		if oldLines > 0 || newLines > 0 {
+			origin := byte(LineContext)
+			switch {
+			case strings.HasPrefix(line, "+"):
+				newLines--
+				current.LineDiff.Added++
+				origin = LineAdded
+			case strings.HasPrefix(line, "-"):
+				oldLines--
+				current.LineDiff.Removed++
+				origin = LineRemoved
+			case strings.HasPrefix(line, "\\"):
+				//"\ No newline at end of file"
+				continue
+			default:
+				oldLines--
+				newLines--
+			}
+			if hunk != nil {
+				content := ""
+				if len(line) > 0 {
+					content = line[1:]
+				}
+				hunk.AddLine(origin, content)
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			gitStyle = true
+			current = &patchFile{}
+			files = append(files, current)
+			if paths := strings.SplitN(line[len("diff --git "):], " b/", 2); len(paths) == 2 {
+				current.OldPath = strings.TrimPrefix(paths[0], "a/")
+				current.NewPath = paths[1]
+			}
+
+		case strings.HasPrefix(line, "Index: "):
+			gitStyle = false
+			current = &patchFile{OldPath: line[len("Index: "):], NewPath: line[len("Index: "):]}
+			files = append(files, current)
+
+		case strings.HasPrefix(line, "--- "):
+			if current == nil {
+				current = &patchFile{}
+				files = append(files, current)
+			}
+			current.OldPath = cleanPatchPath(line[len("--- "):], "a/", gitStyle)
+
+		case strings.HasPrefix(line, "+++ ") && current != nil:
+			current.NewPath = cleanPatchPath(line[len("+++ "):], "b/", gitStyle)
+
+		case strings.HasPrefix(line, "rename from ") && current != nil:
+			current.OldPath = line[len("rename from "):]
+
+		case strings.HasPrefix(line, "rename to ") && current != nil:
+			current.NewPath = line[len("rename to "):]
+
+		case strings.HasPrefix(line, "deleted file mode") && current != nil:
+			current.NewPath = ""
+
+		case strings.HasPrefix(line, "new file mode") && current != nil:
+			current.OldPath = ""
+
+		case strings.HasPrefix(line, "@@ ") && current != nil:
+			var oldStart, newStart int
+			oldStart, oldLines = parseHunkRange(line, "-")
+			newStart, newLines = parseHunkRange(line, "+")
+			if oldStart < 0 || newStart < 0 {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			if KeepHunks {
+				hunk = NewHunk(current.Path(), current.OldPath, oldStart, oldLines, newStart, newLines)
+				current.Hunks = append(current.Hunks, hunk)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+//removes prefixes and suffixes (e.g. "\t(revision 42)") from a path within a diff header
+func cleanPatchPath(path string, prefix string, gitStyle bool) string {
+	if i := strings.Index(path, "\t"); i >= 0 {
+		path = path[:i]
+	}
+	if path == "/dev/null" {
+		return ""
+	}
+	if gitStyle {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	return path
+}
+
+//reads start and length of a range ("-1,5" or "+3") from a hunk header
+func parseHunkRange(header string, marker string) (start int, length int) {
+	for _, field := range strings.Fields(header) {
+		if strings.HasPrefix(field, marker) {
+			length = 1
+			if strings.Contains(field, ",") {
+				if _, err := fmt.Sscanf(field[1:], "%d,%d", &start, &length); err != nil {
+					return -1, 0
+				}
+			} else if _, err := fmt.Sscanf(field[1:], "%d", &start); err != nil {
+				return -1, 0
+			}
+			return start, length
+		}
+	}
+	return -1, 0
+}
@@ -0,0 +1,88 @@
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+//content of a file does not exist (or is corrupt) within the vcs
+var ErrBlobMissing = errors.New("blob missing")
+
+//contents of files are not available for the repository (e.g. an imported git log)
+var ErrNoContents = errors.New("file contents are not available")
+
+//content of a file could not be loaded, matches the wrapped error (e.g. ErrBlobMissing or ErrNoContents)
+type BlobError struct {
+	Id  string
+	Err error
+}
+
+func (e *BlobError) Error() string {
+	return fmt.Sprintf("unable to load file %s: %s", e.Id, e.Err)
+}
+
+func (e *BlobError) Unwrap() error {
+	return e.Err
+}
+
+//blob which does not exist or is corrupt within the object database, the cause is kept in the message
+func missingBlob(id string, err error) *BlobError {
+	return &BlobError{id, fmt.Errorf("%w: %s", ErrBlobMissing, err)}
+}
+
+//changes of a commit (or of a single file, if the path is set) could not be computed
+type DiffError struct {
+	Commit string
+	Parent string
+	Path   string
+	Err    error
+}
+
+func (e *DiffError) Error() string {
+	target := "commit " + e.Commit
+	if e.Path != "" {
+		target = fmt.Sprintf("%s in commit %s", e.Path, e.Commit)
+	}
+	if e.Parent != "" {
+		target += " to parent " + e.Parent
+	}
+	return fmt.Sprintf("unable to diff %s: %s", target, e.Err)
+}
+
+func (e *DiffError) Unwrap() error {
+	return e.Err
+}
+
+//workspace (or a directory within it) could not be created or written
+type WorkspaceError struct {
+	Path string
+	Err  error
+}
+
+func (e *WorkspaceError) Error() string {
+	return fmt.Sprintf("workspace %s is not writable: %s", e.Path, e.Err)
+}
+
+func (e *WorkspaceError) Unwrap() error {
+	return e.Err
+}
+
+//connectors which skip single corrupt objects (e.g. blobs or diffs) to finish loading
+type skippingConnector interface {
+	skippedErrors() []error
+}
+
+//errors of skipped objects, embedded by the connectors
+type skipList struct {
+	skipped []error
+}
+
+func (s *skipList) skip(err error) {
+	log.Printf("skipping: %s", err)
+	s.skipped = append(s.skipped, err)
+}
+
+func (s *skipList) skippedErrors() []error {
+	return s.skipped
+}
@@ -1,10 +1,8 @@
 package vcs
 
 import (
+	"crypto/sha1"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"os"
 )
 
 type FileDiff struct {
@@ -18,33 +16,58 @@ func (diff *FileDiff) IsEmpty() bool {
 }
 
 type File struct {
-	Id          string
-	Size        int64
-	StoragePath string
-	Parents     []*File
+	Id      string
+	Size    int64
+	Parents []*File
+
+	//location of the content within the vcs (connector specific, e.g. "path@revision")
+	source string
+	//loads the content from the vcs, if it is not cached
+	load func() ([]byte, error)
+}
+
+//creates a file object, the content is loaded lazily
+func newFile(id string, size int64, source string, load func() ([]byte, error)) *File {
+	return &File{Id: id, Size: size, source: source, load: load}
 }
 
 func (f *File) String() string {
 	return fmt.Sprintf("%s[%d bytes]", f.Id, f.Size)
 }
 
-// returns file content as string
-func (f *File) Content() string {
+// returns file content as string, a BlobError if the content is missing within the vcs
+func (f *File) Content() (string, error) {
 
-	if content, err := ioutil.ReadFile(f.StoragePath); err != nil {
-		panic(err)
-	} else {
-		return string(content[:])
+	content, err := Blobs.Get(f.Id, f.load)
+	if err != nil {
+		if _, ok := err.(*BlobError); !ok {
+			err = &BlobError{f.Id, err}
+		}
+		return "", err
 	}
+	return string(content[:]), nil
 }
 
-// stores file to local path
-func storeFile(path string, content []byte) {
+//returns the content (at most size bytes, all if size is 0) without adding it to the cache, the complete content is loaded
+func (f *File) peek(size int) (string, error) {
 
-	if _, err := os.Stat(path); err != nil {
-		var perm os.FileMode = 0600
-		if err := ioutil.WriteFile(path, content, perm); err != nil {
-			log.Fatalln(err)
+	content, cached := Blobs.cached(f.Id)
+	if cached == false {
+		var err error
+		if content, err = f.load(); err != nil {
+			return "", err
 		}
 	}
+	if size > 0 && len(content) > size {
+		content = content[:size]
+	}
+	return string(content), nil
+}
+
+// calculates the git blob id of the given content
+func blobId(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return fmt.Sprintf("%x", h.Sum(nil))
 }
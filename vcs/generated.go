@@ -0,0 +1,269 @@
+package vcs
+
+import (
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+//reasons for excluding a file by its content
+const (
+	ExcludedBinary    = "binary"
+	ExcludedGenerated = "generated"
+	ExcludedMinified  = "minified"
+	ExcludedEntropy   = "high entropy"
+)
+
+/*
+markers of generated files within their header, e.g. "@generated by Composer",
+"Code generated by stringer; DO NOT EDIT.", "This class was autogenerated by
+Propel" or "THIS CLASS WAS GENERATED BY THE DOCTRINE ORM"
+*/
+var generatedPattern = regexp.MustCompile(`(?im)@generated\b|` +
+	`^\W*(this|the) (file|class|code) (was|has been|is) (automatically |auto-?)?generated\b|` +
+	`^\W*(code )?(auto-?)?generated (by|from) .*do not edit`)
+
+const (
+	//number of bytes read for the classification (like the check of git for NUL bytes)
+	binaryCheckSize = 8000
+	//number of bytes checked for generated markers
+	headerSize = 1024
+	//files with longer lines on average are minified
+	maxAverageLineLength = 300
+	//files with a higher entropy (bits per byte) are encoded or compressed, normal source code has about 4.5 to 5.5
+	maxEntropy = 6.0
+	//smaller files are not checked for long lines or entropy
+	minHeuristicSize = 1024
+)
+
+/*
+detects binary and generated files by the beginning of their content, returns
+the reason for excluding the file or an empty string: binary files contain NUL
+bytes, generated files have a marker within their header, minified files have
+very long lines and encoded files (e.g. base64 or encrypted) a high entropy
+*/
+func classifyContent(content string) string {
+
+	head := content
+	if len(head) > binaryCheckSize {
+		head = head[:binaryCheckSize]
+	}
+	if strings.IndexByte(head, 0) >= 0 {
+		return ExcludedBinary
+	}
+
+	if len(head) > headerSize {
+		head = head[:headerSize]
+	}
+	if generatedPattern.MatchString(head) {
+		return ExcludedGenerated
+	}
+
+	if len(content) < minHeuristicSize {
+		return ""
+	}
+	if len(content)/lineCount(content) > maxAverageLineLength {
+		return ExcludedMinified
+	}
+	if entropy(content) > maxEntropy {
+		return ExcludedEntropy
+	}
+	return ""
+}
+
+//returns the number of lines of a content (like git, a last line without newline is counted)
+func lineCount(content string) int {
+	if content == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+}
+
+//returns the shannon entropy of a content in bits per byte
+func entropy(content string) float64 {
+
+	counts := [256]int{}
+	for i := 0; i < len(content); i++ {
+		counts[content[i]]++
+	}
+
+	result := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(content))
+			result -= p * math.Log2(p)
+		}
+	}
+	return result
+}
+
+//connectors which detect binary files without reading their contents (e.g. by their diffs)
+type excludingConnector interface {
+	excludedFiles() map[string]string
+}
+
+//binary files detected by the vcs, embedded by the connectors
+type binaryList struct {
+	excluded map[string]string
+}
+
+//reports a file detected as binary by the vcs, it is removed from all commits by the repository
+func (b *binaryList) reportBinary(path string) {
+
+	if DetectGenerated == false {
+		return
+	}
+	if b.excluded == nil {
+		b.excluded = map[string]string{}
+	}
+	if _, exists := b.excluded[path]; exists == false {
+		log.Printf("excluding %s file %s", ExcludedBinary, path)
+	}
+	b.excluded[path] = ExcludedBinary
+}
+
+func (b *binaryList) excludedFiles() map[string]string {
+	return b.excluded
+}
+
+/*
+classifies the files changed by the given commits, only the latest version of
+every path is checked and only its first bytes are classified (the connectors
+load complete contents, but they are not cached, the analyses load them lazily)
+*/
+func (r *Repository) classifyFiles(commits map[string]*Commit) {
+
+	if DetectGenerated == false {
+		return
+	}
+
+	latest := map[string]*File{}
+	dates := map[string]time.Time{}
+	add := func(path string, file *File, date time.Time) {
+		if current, exists := latest[path]; exists &&
+			(date.Before(dates[path]) || date.Equal(dates[path]) && file.Id < current.Id) {
+			return
+		}
+		latest[path], dates[path] = file, date
+	}
+	for _, commit := range commits {
+		for path, file := range commit.Files {
+			add(path, file, commit.Date)
+		}
+		for path, file := range commit.RemovedFiles {
+			add(path, file, commit.Date)
+		}
+	}
+
+	for path, file := range latest {
+		if _, excluded := r.Excluded[path]; excluded || file == nil {
+			continue
+		}
+		content, err := file.peek(binaryCheckSize)
+		if err != nil {
+			continue
+		}
+		if reason := classifyContent(content); reason != "" {
+			log.Printf("excluding %s file %s", reason, path)
+			r.Excluded[path] = reason
+		}
+	}
+}
+
+/*
+removes all changes of the excluded files from the commits, a file is excluded
+for the complete history (even if only some versions were detected), so the
+files of a revision remain consistent
+*/
+func (r *Repository) removeExcludedFiles() {
+
+	if len(r.Excluded) == 0 {
+		return
+	}
+	for _, commit := range r.Commits {
+		for path := range r.Excluded {
+			commit.removeFile(path)
+		}
+	}
+	log.Printf("excluded %d binary or generated files", len(r.Excluded))
+}
+
+/*
+removes all changes of a file from the commit and its diffs, a file moved to
+the path is removed instead (with all its lines) and a file moved from the path
+is added instead
+*/
+func (c *Commit) removeFile(path string) {
+
+	file := c.Files[path]
+	delete(c.Files, path)
+	delete(c.AddedFiles, path)
+	delete(c.ChangedFiles, path)
+	delete(c.RemovedFiles, path)
+
+	for _, diff := range c.Diffs {
+		lines := diff.FileLines[path]
+		diff.LineDiff.Added -= lines.Added
+		diff.LineDiff.Removed -= lines.Removed
+		c.LineDiff.Added -= lines.Added
+		c.LineDiff.Removed -= lines.Removed
+
+		delete(diff.FileLines, path)
+		delete(diff.Hunks, path)
+		delete(diff.AddedFiles, path)
+		delete(diff.ChangedFiles, path)
+		delete(diff.RemovedFiles, path)
+		delete(diff.ExistingFiles, path)
+
+		for oldPath, newPath := range diff.MovedFiles {
+			switch path {
+			case newPath:
+				delete(diff.MovedFiles, oldPath)
+				if oldFile := previousVersion(file); oldFile != nil {
+					diff.RemovedFiles[oldPath] = oldFile
+					c.RemovedFiles[oldPath] = oldFile
+					if content, err := oldFile.peek(0); err == nil {
+						lines := LineDiff{0, lineCount(content)}
+						diff.AddLines(oldPath, lines)
+						c.LineDiff.Add(lines)
+					}
+				}
+			case oldPath:
+				delete(diff.MovedFiles, oldPath)
+				if newFile, exists := c.Files[newPath]; exists {
+					delete(diff.ChangedFiles, newPath)
+					delete(c.ChangedFiles, newPath)
+					diff.AddedFiles[newPath] = newFile
+					c.AddedFiles[newPath] = newFile
+				}
+			}
+		}
+	}
+
+	for oldPath, newPath := range c.MovedFiles {
+		if oldPath == path || newPath == path {
+			delete(c.MovedFiles, oldPath)
+		}
+	}
+}
+
+//returns the version of a file before it was changed (the file itself, if it was only moved)
+func previousVersion(file *File) *File {
+	if file != nil && len(file.Parents) > 0 {
+		return file.Parents[0]
+	}
+	return file
+}
+
+//returns the paths of the excluded files in order
+func (r *Repository) ExcludedPaths() []string {
+	paths := []string{}
+	for path := range r.Excluded {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
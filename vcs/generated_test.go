@@ -0,0 +1,84 @@
+package vcs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyContent(t *testing.T) {
+
+	//every byte except NUL, the lines are short, but the entropy is high
+	encoded := []byte{}
+	for n := 0; n < 8; n++ {
+		for c := 1; c < 256; c++ {
+			encoded = append(encoded, byte(c))
+		}
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		reason  string
+	}{
+		{"empty", "", ""},
+		{"code", strings.Repeat("function f() { return 1; }\n", 100), ""},
+		{"binary", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", ExcludedBinary},
+		{"binary after text", strings.Repeat("a\n", 1000) + "\x00", ExcludedBinary},
+		{"composer", "<?php\n\n// @generated by Composer\nreturn [];\n", ExcludedGenerated},
+		{"go", "// Code generated by stringer; DO NOT EDIT.\n\npackage main\n", ExcludedGenerated},
+		{"propel", "<?php\n/**\n * This class was autogenerated by Propel on:\n */\n", ExcludedGenerated},
+		{"automatically", "# The file was automatically generated from schema.xml\n", ExcludedGenerated},
+		{"marker within a line", "// the code is not generated by a tool, do not edit\n", ""},
+		{"marker after the header", strings.Repeat("a\n", 600) + "// @generated\n", ""},
+		{"minified", strings.Repeat("var a=function(){return 1};", 100) + "\n", ExcludedMinified},
+		{"short line", strings.Repeat("a", 500), ""},
+		{"entropy", string(encoded), ExcludedEntropy},
+		{"short encoded", string(encoded[:500]), ""},
+	}
+
+	for _, test := range tests {
+		if reason := classifyContent(test.content); reason != test.reason {
+			t.Errorf("expected %q for %s, got %q", test.reason, test.name, reason)
+		}
+	}
+}
+
+/*
+a file is moved into an excluded directory and another one is moved out of it,
+the first move becomes a removal (with all its lines) and the second one an
+addition
+*/
+func TestRemoveExcludedFiles(t *testing.T) {
+
+	Filter = PassThroughFilter{}
+	b := NewBuilder()
+	add := b.Commit(CommitSpec{Author: "alice@example.com", Date: testDate(1), Message: "add a and b",
+		Write: map[string]string{"src/a.js": "a\nb\nc\n", "dist/b.js": "b\n"}})
+	move := b.Commit(CommitSpec{Author: "alice@example.com", Date: testDate(2), Message: "move a and b",
+		Parents: []*Commit{add}, Rename: map[string]string{"src/a.js": "dist/a.js", "dist/b.js": "src/b.js"}})
+
+	repo := b.Repository()
+	repo.Excluded["dist/a.js"] = ExcludedGenerated
+	repo.Excluded["dist/b.js"] = ExcludedGenerated
+	repo.removeExcludedFiles()
+
+	if len(add.Files) != 1 || add.AddedFiles["src/a.js"] == nil || add.LineDiff != (LineDiff{3, 0}) {
+		t.Errorf("expected only src/a.js to be added, got %v %v", add.AddedFiles, add.LineDiff)
+	}
+
+	if len(move.MovedFiles) > 0 || len(move.Diffs[0].MovedFiles) > 0 {
+		t.Errorf("expected no moves, got %v %v", move.MovedFiles, move.Diffs[0].MovedFiles)
+	}
+	if len(move.RemovedFiles) != 1 || move.RemovedFiles["src/a.js"] != add.Files["src/a.js"] {
+		t.Errorf("expected src/a.js to be removed, got %v", move.RemovedFiles)
+	}
+	if len(move.AddedFiles) != 1 || move.AddedFiles["src/b.js"] == nil || len(move.ChangedFiles) > 0 {
+		t.Errorf("expected src/b.js to be added, got %v %v", move.AddedFiles, move.ChangedFiles)
+	}
+	if len(move.Files) != 1 || move.Files["src/b.js"] == nil {
+		t.Errorf("expected only src/b.js at the last commit, got %v", move.Files)
+	}
+	if move.LineDiff != (LineDiff{0, 3}) || move.Diffs[0].FileLines["src/a.js"] != (LineDiff{0, 3}) {
+		t.Errorf("expected the 3 lines of src/a.js to be removed, got %v %v", move.LineDiff, move.Diffs[0].FileLines)
+	}
+}
@@ -0,0 +1,696 @@
+// +build cgo
+
+package vcs
+
+import (
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	gitBackends[LibGit2] = func() Connector {
+		return &GitConnector{}
+	}
+}
+
+//commit whose diffs are not loaded yet
+type gitPendingCommit struct {
+	commit    *Commit
+	treeId    *git.Oid
+	parentIds []*git.Oid
+}
+
+//changes between a commit and one of its parents, computed by the diff workers
+type gitDiff struct {
+	deltas    []git.DiffDelta
+	fileLines map[string]LineDiff
+	hunks     []*Hunk
+}
+
+//internal struct for this connecotr
+type GitConnector struct {
+	repo        *git.Repository
+	odb         *git.Odb
+	storagePath string
+	commits     map[string]*Commit
+	developers  map[string]*Developer
+	files       map[string]*File
+	//commits whose parents are cut off by a shallow clone
+	boundaries map[string]bool
+	skipList
+	pathAttributes
+	binaryList
+}
+
+func (c *GitConnector) LoadLocal(path string, workspace string) error {
+
+	repo, err := git.OpenRepository(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.init(repo, workspace); err != nil {
+		return err
+	}
+
+	log.Printf("opened local git repo in %s", path)
+	return nil
+}
+
+//loads an existing repository or clone it from external source
+func (c *GitConnector) LoadRemote(path string, workspace string) error {
+
+	//the clone of a previous run is updated, it is only cloned again if it is missing or corrupt
+	repo, err := c.openClone(workspace)
+	if err == nil {
+		//libgit2 is not able to fetch shallow, so the git client is used
+		repo.Free()
+		if err := fetchClone(workspace); err != nil {
+			return err
+		}
+		if repo, err = git.OpenRepository(workspace); err != nil {
+			return err
+		}
+		log.Printf("fetched remote git repo from %s to %s", path, workspace)
+	} else {
+		if hasWorkspaceInfo(workspace) {
+			log.Printf("unable to reuse workspace %s: %s", workspace, err)
+		}
+
+		//clear workspace
+		os.RemoveAll(workspace)
+		if repo, err = c.cloneGitRepo(path, workspace); err != nil {
+			return err
+		}
+		log.Printf("cloned remote git repo from %s to %s", path, workspace)
+	}
+
+	return c.init(repo, workspace)
+}
+
+//opens the clone of a previous run within the workspace
+func (c *GitConnector) openClone(workspace string) (*git.Repository, error) {
+
+	if hasWorkspaceInfo(workspace) == false {
+		return nil, fmt.Errorf("no clone in %s", workspace)
+	}
+	repo, err := git.OpenRepository(workspace)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err == nil {
+		_, err = repo.LookupCommit(head.Target())
+	}
+	if err != nil {
+		repo.Free()
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (c *GitConnector) init(repo *git.Repository, workspace string) error {
+
+	c.repo = repo
+	c.storagePath = workspace
+
+	odb, err := repo.Odb()
+	if err != nil {
+		return err
+	}
+	c.odb = odb
+
+	if c.boundaries, err = readShallowFile(repo.Path()); err != nil {
+		return err
+	}
+
+	var fm os.FileMode = 0700
+	if err := os.MkdirAll(c.storagePath, fm); err != nil {
+		return &WorkspaceError{c.storagePath, err}
+	}
+
+	//the model could already be restored from a snapshot
+	if c.commits == nil {
+		c.developers = map[string]*Developer{}
+		c.commits = map[string]*Commit{}
+		c.files = map[string]*File{}
+	}
+
+	c.loadPathAttributes(c)
+
+	if err := c.fetchAll(); err != nil {
+		return err
+	}
+
+	log.Printf("loaded %d commits, %d delevopers and %d different files from git repo",
+		len(c.commits), len(c.developers), len(c.files))
+
+	return nil
+}
+
+func (c *GitConnector) Developers() map[string]*Developer {
+	return c.developers
+}
+
+func (c *GitConnector) Commits() map[string]*Commit {
+	return c.commits
+}
+
+func (c *GitConnector) resume(commits map[string]*Commit, developers map[string]*Developer, files map[string]*File) {
+	c.commits = commits
+	c.developers = developers
+	c.files = files
+}
+
+//loads all commits reachable from the selected revision
+func (c *GitConnector) fetchAll() error {
+
+	if c.repo == nil {
+		return fmt.Errorf("no git repository loaded")
+	}
+
+	_, revision := splitRange(Revision)
+	headCommit, err := c.resolve(revision)
+	if err != nil {
+		return err
+	}
+
+	pending, err := c.walkCommits(headCommit)
+	if err != nil {
+		return err
+	}
+
+	//diffs are computed in parallel, but added to the commits in the order of the walk
+	diffs := c.diffCommits(pending)
+	for n, crt := range pending {
+		for i, diff := range diffs[n] {
+			parentId := ""
+			if i < len(crt.parentIds) {
+				parentId = crt.parentIds[i].String()
+			}
+			c.addDiffToCommit(crt.commit, parentId, diff)
+		}
+	}
+
+	return nil
+}
+
+func (c *GitConnector) Resolve(revision string) (string, error) {
+	commit, err := c.resolve(revision)
+	if err != nil {
+		return "", err
+	}
+	return commit.Id().String(), nil
+}
+
+//returns the tags of all loaded commits
+func (c *GitConnector) loadTags() ([]*Tag, error) {
+
+	tags := []*Tag{}
+	err := c.repo.Tags.Foreach(func(name string, id *git.Oid) error {
+		var date time.Time
+
+		//annotated tags have an own object
+		if tag, err := c.repo.LookupTag(id); err == nil {
+			id = tag.TargetId()
+			date = tag.Tagger().When
+			tag.Free()
+		}
+
+		if commit, exists := c.commits[id.String()]; exists {
+			if date.IsZero() {
+				date = commit.Date
+			}
+			tags = append(tags, &Tag{strings.TrimPrefix(name, "refs/tags/"), commit, date})
+		}
+		return nil
+	})
+
+	return tags, err
+}
+
+//reads a file of the analyzed revision
+func (c *GitConnector) ReadFile(path string) ([]byte, error) {
+	_, revision := splitRange(Revision)
+	commit, err := c.resolve(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := tree.EntryByPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := c.repo.LookupBlob(entry.Id)
+	if err != nil {
+		return nil, err
+	}
+	return blob.Contents(), nil
+}
+
+//looks up the commit of a branch, tag or commit id (HEAD if empty)
+func (c *GitConnector) resolve(revision string) (*git.Commit, error) {
+
+	if revision == "" {
+		revision = "HEAD"
+	}
+
+	obj, err := c.repo.RevparseSingle(revision)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve revision %s: %s", revision, err)
+	}
+
+	commitObj, err := obj.Peel(git.ObjectCommit)
+	if err != nil {
+		return nil, fmt.Errorf("revision %s does not point to a commit: %s", revision, err)
+	}
+
+	return c.repo.LookupCommit(commitObj.Id())
+}
+
+/*
+creates the internal commit objects for all commits reachable from the given
+one and links them to their parents, commits which are already known (e.g. of
+a snapshot) are not walked again
+*/
+func (c *GitConnector) walkCommits(headCommit *git.Commit) ([]*gitPendingCommit, error) {
+
+	pending := []*gitPendingCommit{}
+	if _, exists := c.commits[headCommit.Id().String()]; exists {
+		return pending, nil
+	}
+
+	stack := []*git.Commit{headCommit}
+	c.createCommit(headCommit)
+	for len(stack) > 0 {
+		gitCommit := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		crt := &gitPendingCommit{commit: c.commits[gitCommit.Id().String()], treeId: gitCommit.TreeId()}
+		pending = append(pending, crt)
+
+		//the parents of a boundary commit are missing, it is loaded like a root commit
+		parentCount := gitCommit.ParentCount()
+		if crt.commit.Boundary {
+			parentCount = 0
+		}
+		if parentCount > 1 {
+			c.detectSubtrees(crt.commit, gitCommit)
+		}
+
+		for n := uint(0); n < parentCount; n++ {
+			parentId := gitCommit.ParentId(n)
+			if crt.commit.skipsSubtree(parentId.String()) {
+				continue
+			}
+			crt.parentIds = append(crt.parentIds, parentId)
+
+			if _, exists := c.commits[parentId.String()]; !exists {
+				parentGitCommit, err := c.repo.LookupCommit(parentId)
+				if err != nil {
+					return nil, err
+				}
+				c.createCommit(parentGitCommit)
+				stack = append(stack, parentGitCommit)
+			}
+		}
+	}
+
+	for _, crt := range pending {
+		for _, parentId := range crt.parentIds {
+			parentCommit := c.commits[parentId.String()]
+			crt.commit.Parents[parentCommit.Id] = parentCommit
+			parentCommit.Children[crt.commit.Id] = crt.commit
+		}
+	}
+
+	return pending, nil
+}
+
+//creates an internal commit object based on the git2go commit
+func (c *GitConnector) createCommit(gitCommit *git.Commit) *Commit {
+	author := gitCommit.Author()
+
+	dev, exists := c.developers[author.Email]
+	if !exists {
+		dev = NewDeveloper(author.Email, author.Email, author.Name)
+		c.developers[author.Email] = dev
+	}
+
+	commit := NewCommit(gitCommit.Id().String(), gitCommit.Message(), author.When, dev)
+
+	c.commits[commit.Id] = commit
+	dev.Commits[commit.Id] = commit
+	commit.Boundary = c.boundaries[commit.Id]
+
+	committer := gitCommit.Committer()
+	commit.SetCommitter(developerFor(c.developers, committer.Email, committer.Email, committer.Name))
+	commit.readCoAuthors(c.developers)
+
+	return commit
+}
+
+//detects the parents (except the first one) which were merged as subtree
+func (c *GitConnector) detectSubtrees(commit *Commit, gitCommit *git.Commit) {
+
+	tree, err := gitCommit.Tree()
+	if err != nil {
+		return
+	}
+
+	var dirs map[string]treeDir
+	for n := uint(1); n < gitCommit.ParentCount(); n++ {
+		parentGitCommit := gitCommit.Parent(n)
+		if parentGitCommit == nil {
+			continue
+		}
+		parentTree, err := parentGitCommit.Tree()
+		if err != nil {
+			continue
+		}
+
+		if dirs == nil {
+			dirs = gitTreeDirs(tree)
+		}
+		parent := treeDir{parentTree.Id().String(), map[string]string{}}
+		for i := uint64(0); i < parentTree.EntryCount(); i++ {
+			entry := parentTree.EntryByIndex(i)
+			parent.entries[entry.Name] = entry.Id.String()
+		}
+
+		if prefix := subtreePrefix(dirs, parent); prefix != "" {
+			log.Printf("commit %s merges %s as subtree %s", commit.Id, parentGitCommit.Id(), prefix)
+			commit.Subtrees[parentGitCommit.Id().String()] = prefix
+		}
+	}
+}
+
+//returns all directories of a tree by path (the root directory is empty)
+func gitTreeDirs(tree *git.Tree) map[string]treeDir {
+
+	dirs := map[string]treeDir{"": {tree.Id().String(), map[string]string{}}}
+	tree.Walk(func(root string, entry *git.TreeEntry) int {
+		if entry.Filemode == git.FilemodeTree {
+			dirs[root+entry.Name] = treeDir{entry.Id.String(), map[string]string{}}
+		}
+		if parent, exists := dirs[strings.TrimSuffix(root, "/")]; exists {
+			parent.entries[entry.Name] = entry.Id.String()
+		}
+		return 0
+	})
+	return dirs
+}
+
+//returns the commits of all submodules (gitlinks) of the analyzed revision by path
+func (c *GitConnector) gitlinks() (map[string]string, error) {
+
+	_, revision := splitRange(Revision)
+	commit, err := c.resolve(revision)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	gitlinks := map[string]string{}
+	err = tree.Walk(func(root string, entry *git.TreeEntry) int {
+		if entry.Filemode == git.FilemodeCommit {
+			gitlinks[root+entry.Name] = entry.Id.String()
+		}
+		return 0
+	})
+	return gitlinks, err
+}
+
+/*
+computes the diffs of all commits with a pool of workers, the results are in the
+order of the commits, commits which cannot be diffed are skipped with empty diffs
+*/
+func (c *GitConnector) diffCommits(pending []*gitPendingCommit) [][]*gitDiff {
+
+	workers := Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	diffs := make([][]*gitDiff, len(pending))
+	errs := make([]error, len(pending))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				diffs[n], errs[n] = c.diffCommit(pending[n])
+			}
+		}()
+	}
+
+	for n := range pending {
+		jobs <- n
+	}
+	close(jobs)
+	wg.Wait()
+
+	for n, err := range errs {
+		if err != nil {
+			c.skip(&DiffError{Commit: pending[n].commit.Id, Err: err})
+			diffs[n] = []*gitDiff{}
+			for i := 0; i == 0 || i < len(pending[n].parentIds); i++ {
+				diffs[n] = append(diffs[n], &gitDiff{fileLines: map[string]LineDiff{}})
+			}
+		}
+	}
+	return diffs
+}
+
+//computes the diffs of a commit to each of its parents (to an empty tree for root commits)
+func (c *GitConnector) diffCommit(crt *gitPendingCommit) ([]*gitDiff, error) {
+
+	tree, err := c.repo.LookupTree(crt.treeId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(crt.parentIds) == 0 {
+		diff, err := c.diffTrees(nil, tree)
+		return []*gitDiff{diff}, err
+	}
+
+	diffs := []*gitDiff{}
+	for _, parentId := range crt.parentIds {
+		parentGitCommit, err := c.repo.LookupCommit(parentId)
+		if err != nil {
+			return nil, err
+		}
+		parentTree, err := parentGitCommit.Tree()
+		if err != nil {
+			return nil, err
+		}
+
+		diff, err := c.diffTrees(parentTree, tree)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+/*
+collects the changed files (with detected renames) and the changed lines between
+two trees, submodules (gitlinks) are no files and ignored
+*/
+func (c *GitConnector) diffTrees(parentTree *git.Tree, newTree *git.Tree) (*gitDiff, error) {
+
+	diffOpt, err := git.DefaultDiffOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := c.repo.DiffTreeToTree(parentTree, newTree, &diffOpt)
+	if err != nil {
+		return nil, err
+	}
+	defer diff.Free()
+
+	findOpts, err := git.DefaultDiffFindOptions()
+	if err != nil {
+		return nil, err
+	}
+	if err := diff.FindSimilar(&findOpts); err != nil {
+		return nil, err
+	}
+
+	result := &gitDiff{fileLines: map[string]LineDiff{}}
+	err = diff.ForEach(func(delta git.DiffDelta, progress float64) (git.DiffForEachHunkCallback, error) {
+		valid := c.validPath(delta.NewFile.Path) &&
+			delta.OldFile.Mode != uint16(git.FilemodeCommit) && delta.NewFile.Mode != uint16(git.FilemodeCommit)
+		if valid {
+			result.deltas = append(result.deltas, delta)
+		}
+
+		return func(gitHunk git.DiffHunk) (git.DiffForEachLineCallback, error) {
+			var hunk *Hunk
+			if valid && KeepHunks {
+				oldPath := delta.OldFile.Path
+				if delta.OldFile.Oid.IsZero() {
+					oldPath = ""
+				}
+				hunk = NewHunk(delta.NewFile.Path, oldPath,
+					gitHunk.OldStart, gitHunk.OldLines, gitHunk.NewStart, gitHunk.NewLines)
+				result.hunks = append(result.hunks, hunk)
+			}
+
+			return func(line git.DiffLine) error {
+				if valid {
+					lines := result.fileLines[delta.NewFile.Path]
+					switch line.Origin {
+					case git.DiffLineAddition:
+						lines.Added++
+					case git.DiffLineDeletion:
+						lines.Removed++
+					case git.DiffLineContext:
+					default:
+						//markers like "no newline at end of file"
+						return nil
+					}
+					result.fileLines[delta.NewFile.Path] = lines
+					if hunk != nil {
+						hunk.AddLine(byte(line.Origin), line.Content)
+					}
+				}
+				return nil
+			}, nil
+		}, nil
+	}, git.DiffDetailLines)
+
+	return result, err
+}
+
+//adds the changed files and lines compared to a parent (empty for root commits) to the commit
+func (c *GitConnector) addDiffToCommit(commit *Commit, parentId string, result *gitDiff) {
+
+	diff := NewParentDiff(parentId)
+	for _, delta := range result.deltas {
+		filepath := delta.NewFile.Path
+		oldFilepath := delta.OldFile.Path
+
+		var file, oldFile *File
+
+		if delta.OldFile.Oid.IsZero() == false {
+			oldFile = c.loadFile(delta.OldFile.Oid)
+		}
+
+		if delta.NewFile.Oid.IsZero() {
+			diff.RemovedFiles[oldFilepath] = oldFile
+		} else {
+			file = c.loadFile(delta.NewFile.Oid)
+			commit.Files[filepath] = file
+		}
+
+		//files flagged as binary by libgit2 are removed by the repository (for all commits)
+		if delta.NewFile.Flags&git.DiffFlagBinary != 0 {
+			c.reportBinary(filepath)
+		}
+		if delta.OldFile.Flags&git.DiffFlagBinary != 0 {
+			c.reportBinary(oldFilepath)
+		}
+
+		switch delta.Status {
+		case git.DeltaModified:
+			diff.ChangedFiles[filepath] = file
+			file.Parents = append(file.Parents, oldFile)
+		case git.DeltaAdded:
+			diff.AddedFiles[filepath] = file
+		case git.DeltaRenamed:
+			diff.MovedFiles[oldFilepath] = filepath
+			if delta.Similarity != 100 {
+				diff.ChangedFiles[filepath] = file
+				file.Parents = append(file.Parents, oldFile)
+			}
+		}
+	}
+
+	for path, lines := range result.fileLines {
+		diff.AddLines(path, lines)
+	}
+	for _, hunk := range result.hunks {
+		diff.AddHunk(hunk)
+	}
+
+	commit.AddDiff(diff)
+}
+
+/*
+creates a file object for a blob, the content is loaded on demand, missing or
+corrupt blobs are skipped: the file is created without size and its content
+returns a BlobError
+*/
+func (c *GitConnector) loadFile(oid *git.Oid) *File {
+
+	if file, exists := c.files[oid.String()]; exists {
+		return file
+	}
+
+	size, _, err := c.odb.ReadHeader(oid)
+	if err != nil {
+		c.skip(missingBlob(oid.String(), err))
+		size = 0
+	}
+
+	file := newFile(oid.String(), int64(size), "", c.fileLoader(oid.String(), ""))
+	c.files[oid.String()] = file
+	return file
+}
+
+//blobs are loaded by their id, no further source is needed
+func (c *GitConnector) fileLoader(id string, source string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		oid, err := git.NewOid(id)
+		if err != nil {
+			return nil, &BlobError{id, err}
+		}
+		blob, err := c.repo.LookupBlob(oid)
+		if err != nil {
+			return nil, missingBlob(id, err)
+		}
+		return blob.Contents(), nil
+	}
+}
+
+func (c GitConnector) cloneGitRepo(external string, local string) (*git.Repository, error) {
+
+	//libgit2 is not able to clone shallow, so the git client is used
+	if isShallowClone() {
+		if err := cloneShallow(external, local); err != nil {
+			return nil, err
+		}
+		return git.OpenRepository(local)
+	}
+
+	checkoutOpts := &git.CheckoutOpts{Strategy: git.CheckoutForce}
+	cloneOpts := &git.CloneOptions{CheckoutOpts: checkoutOpts, Bare: true}
+	repo, err := git.Clone(external, local, cloneOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get git repository: %s", err)
+	}
+	return repo, nil
+}
+
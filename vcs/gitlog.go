@@ -0,0 +1,660 @@
+package vcs
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+formats of the "Date:" line of a git log export (--date=default, iso,
+iso-strict, rfc, raw and short)
+*/
+var gitLogDateLayouts = []string{
+	"Mon Jan 2 15:04:05 2006 -0700",
+	"2006-01-02 15:04:05 -0700",
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"2006-01-02",
+}
+
+var (
+	gitLogPersonPattern  = regexp.MustCompile(`^(.*?)\s*<([^>]*)>$`)
+	gitLogNumstatPattern = regexp.MustCompile(`^(\d+|-)\t(\d+|-)\t(.+)$`)
+	gitLogSummaryPattern = regexp.MustCompile(`^ (create|delete) mode \d+ (.+)$`)
+	gitLogRenamePattern  = regexp.MustCompile(`^ (rename|copy) (.+) \(\d+%\)$`)
+	//first line of the --format layout, the commit id and its parents ("%H %P")
+	gitLogFormatPattern = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?( [0-9a-f]{40}([0-9a-f]{24})?)*$`)
+)
+
+//layout of --format exports, the message (%B) is optional
+const gitLogFormat = "%H %P%n%an <%ae>%n%ad%n%B"
+
+/*
+connector for text exports of the git history, as created by
+
+	git log --parents --numstat --summary -M
+	git log --format='%H %P%n%an <%ae>%n%ad%n%B' --numstat --summary -M
+
+(other --pretty formats like fuller and the --date formats default, iso,
+iso-strict, rfc, raw and short are supported as well, --numstat is required),
+without --parents the history is assumed to be linear (except merges with a
+"Merge:" line), without --summary files are added when they are changed for the
+first time and removed files are unknown, the contents of files are not part of
+the export
+*/
+type GitLogConnector struct {
+	commits    map[string]*Commit
+	developers map[string]*Developer
+	files      map[string]*File
+	entries    []*gitLogEntry
+	//branches and tags of the --decorate output
+	refs map[string]string
+	tags map[string]string
+	//parents within the export and all parents (including the cut off ones, which are not part of the export)
+	parents     map[string][]string
+	diffParents map[string][]string
+	binaryList
+}
+
+//commit of the export, which is not linked to its parents yet
+type gitLogEntry struct {
+	id             string
+	parents        []string
+	mergeParents   []string
+	authorName     string
+	authorEmail    string
+	committerName  string
+	committerEmail string
+	date           time.Time
+	message        []string
+	//changes by parent (-m prints a block for every parent, "" if the parent is not named)
+	changes map[string]*gitLogChanges
+}
+
+//changes of a commit compared to one of its parents in order of the export
+type gitLogChanges struct {
+	paths   []string
+	changes map[string]*gitLogChange
+}
+
+type gitLogChange struct {
+	oldPath string
+	lines   LineDiff
+	created bool
+	deleted bool
+	//numstat without line counts ("-")
+	binary bool
+}
+
+func (c *GitLogConnector) LoadLocal(path string, workspace string) error {
+
+	c.developers = map[string]*Developer{}
+	c.commits = map[string]*Commit{}
+	c.files = map[string]*File{}
+
+	if err := c.parse(path); err != nil {
+		return err
+	}
+	c.linkEntries()
+
+	if err := c.fetchAll(); err != nil {
+		return err
+	}
+
+	log.Printf("imported %d commits, %d delevopers and %d different files from git log %s",
+		len(c.commits), len(c.developers), len(c.files), path)
+	return nil
+}
+
+func (c *GitLogConnector) LoadRemote(path string, workspace string) error {
+	return fmt.Errorf("%s: git log exports are only read from local files", ErrUnsupportedSystem)
+}
+
+func (c *GitLogConnector) Developers() map[string]*Developer {
+	return c.developers
+}
+
+func (c *GitLogConnector) Commits() map[string]*Commit {
+	return c.commits
+}
+
+//resolves a branch or tag of the decorations or an (abbreviated) commit id, the latest commit if empty
+func (c *GitLogConnector) Resolve(revision string) (string, error) {
+
+	if revision == "" || revision == "HEAD" {
+		if id, exists := c.refs["HEAD"]; exists {
+			return id, nil
+		}
+		heads := c.heads()
+		if len(heads) == 0 {
+			return "", fmt.Errorf("unable to resolve revision %s: empty git log", revision)
+		}
+		return heads[len(heads)-1].id, nil
+	}
+
+	if id, exists := c.refs[revision]; exists {
+		return id, nil
+	}
+	if id, exists := c.tags[revision]; exists {
+		return id, nil
+	}
+	if id := c.lookup(revision); id != "" {
+		return id, nil
+	}
+	return "", fmt.Errorf("unable to resolve revision %s", revision)
+}
+
+//returns the tags of the decorations, the date of a tag is the date of its commit
+func (c *GitLogConnector) loadTags() ([]*Tag, error) {
+	tags := []*Tag{}
+	for name, id := range c.tags {
+		if commit, exists := c.commits[id]; exists {
+			tags = append(tags, &Tag{name, commit, commit.Date})
+		}
+	}
+	return tags, nil
+}
+
+//files are identified by their commit and path ("commit:path"), their content is not available
+func (c *GitLogConnector) fileLoader(id string, source string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		return nil, &BlobError{id, ErrNoContents}
+	}
+}
+
+//reads all commits of the export
+func (c *GitLogConnector) parse(path string) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	c.refs = map[string]string{}
+	c.tags = map[string]string{}
+	entries := map[string]*gitLogEntry{}
+
+	var entry *gitLogEntry
+	var changes *gitLogChanges
+	inMessage := false
+	//remaining header lines of the --format layout (author and date)
+	formatted, header := false, 0
+	numstat := false
+
+	start := func(id string, parents []string, from string) {
+		//-m prints the commit again for every parent
+		var exists bool
+		if entry, exists = entries[id]; !exists {
+			entry = &gitLogEntry{id: id, parents: parents, changes: map[string]*gitLogChanges{}}
+			entries[id] = entry
+			c.entries = append(c.entries, entry)
+		}
+		if entry.changes[from] == nil {
+			entry.changes[from] = &gitLogChanges{changes: map[string]*gitLogChange{}}
+		}
+		changes = entry.changes[from]
+		inMessage = false
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(line, "commit ") {
+			id, parents, from, decorations := parseGitLogCommitLine(line[len("commit "):])
+			start(id, parents, from)
+			c.addDecorations(id, decorations)
+			formatted = false
+			continue
+		}
+
+		if (entry == nil || formatted) && gitLogFormatPattern.MatchString(strings.TrimSpace(line)) {
+			fields := strings.Fields(line)
+			start(fields[0], fields[1:], "")
+			formatted, header = true, 2
+			continue
+		}
+
+		if entry == nil {
+			if strings.TrimSpace(line) != "" {
+				return fmt.Errorf("unable to read git log %s: line %d is not part of a commit "+
+					"(export with --pretty=medium or --format='%s' and --numstat)", path, n, gitLogFormat)
+			}
+			continue
+		}
+
+		if formatted && header > 0 {
+			if header == 2 {
+				entry.authorName, entry.authorEmail = parseGitLogPerson(line)
+			} else if entry.date, err = parseGitLogDate(strings.TrimSpace(line)); err != nil {
+				return fmt.Errorf("unable to read git log %s: invalid date in line %d: %s", path, n, err)
+			}
+			header--
+			continue
+		}
+
+		//the message of the --format layout is not indented, it ends with the first changed file
+		if formatted && len(changes.paths) == 0 && gitLogNumstatPattern.MatchString(line) == false &&
+			gitLogSummaryPattern.MatchString(line) == false && gitLogRenamePattern.MatchString(line) == false {
+			entry.message = append(entry.message, line)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "    "):
+			inMessage = true
+			if len(entry.changes) == 1 {
+				entry.message = append(entry.message, line[4:])
+			}
+
+		case line == "":
+			//empty lines within the message are indented as well, except for some exports
+			if inMessage && len(entry.changes) == 1 {
+				entry.message = append(entry.message, "")
+			}
+
+		case strings.HasPrefix(line, "Merge:"):
+			entry.mergeParents = strings.Fields(line[len("Merge:"):])
+
+		case strings.HasPrefix(line, "Author:"):
+			entry.authorName, entry.authorEmail = parseGitLogPerson(line[len("Author:"):])
+
+		case strings.HasPrefix(line, "Commit:"):
+			entry.committerName, entry.committerEmail = parseGitLogPerson(line[len("Commit:"):])
+
+		case strings.HasPrefix(line, "Date:"), strings.HasPrefix(line, "AuthorDate:"):
+			value := strings.TrimSpace(line[strings.Index(line, ":")+1:])
+			if entry.date, err = parseGitLogDate(value); err != nil {
+				return fmt.Errorf("unable to read git log %s: invalid date in line %d: %s", path, n, err)
+			}
+
+		case gitLogNumstatPattern.MatchString(line):
+			numstat = true
+			match := gitLogNumstatPattern.FindStringSubmatch(line)
+			oldPath, newPath := splitGitLogRename(match[3])
+			change := changes.get(newPath)
+			added, _ := strconv.Atoi(match[1])
+			removed, _ := strconv.Atoi(match[2])
+			change.lines.Add(LineDiff{added, removed})
+			change.binary = match[1] == "-"
+			if oldPath != newPath {
+				change.oldPath = oldPath
+			}
+
+		case gitLogSummaryPattern.MatchString(line):
+			match := gitLogSummaryPattern.FindStringSubmatch(line)
+			change := changes.get(unquoteGitLogPath(match[2]))
+			change.created = match[1] == "create"
+			change.deleted = match[1] == "delete"
+
+		case gitLogRenamePattern.MatchString(line):
+			match := gitLogRenamePattern.FindStringSubmatch(line)
+			oldPath, newPath := splitGitLogRename(match[2])
+			change := changes.get(newPath)
+			if match[1] == "rename" {
+				change.oldPath = oldPath
+			} else {
+				change.created = true
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	//e.g. --stat or --oneline exports without line counts
+	if len(c.entries) > 0 && numstat == false {
+		return fmt.Errorf("unable to read git log %s: no changed files found, the export needs --numstat", path)
+	}
+	return nil
+}
+
+//returns the change of a path, it is created if necessary
+func (changes *gitLogChanges) get(path string) *gitLogChange {
+	if _, exists := changes.changes[path]; !exists {
+		changes.changes[path] = &gitLogChange{}
+		changes.paths = append(changes.paths, path)
+	}
+	return changes.changes[path]
+}
+
+//collects the branches and tags of a --decorate output, e.g. "HEAD -> master, tag: v1.0, origin/master"
+func (c *GitLogConnector) addDecorations(id string, decorations string) {
+	for _, decoration := range strings.Split(decorations, ",") {
+		decoration = strings.TrimSpace(decoration)
+		switch {
+		case decoration == "":
+		case strings.HasPrefix(decoration, "tag: "):
+			c.tags[strings.TrimPrefix(decoration, "tag: ")] = id
+		case strings.HasPrefix(decoration, "HEAD -> "):
+			c.refs["HEAD"] = id
+			c.refs[strings.TrimPrefix(decoration, "HEAD -> ")] = id
+		default:
+			c.refs[decoration] = id
+		}
+	}
+}
+
+//returns the commits of the export ordered by date, git log lists children first, which decides the order of commits with the same date
+func (c *GitLogConnector) dateOrder() []*gitLogEntry {
+	entries := []*gitLogEntry{}
+	for n := len(c.entries) - 1; n >= 0; n-- {
+		entries = append(entries, c.entries[n])
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].date.Before(entries[j].date) })
+	return entries
+}
+
+//resolves the parents of all commits, without --parents the history is linear
+func (c *GitLogConnector) linkEntries() {
+
+	c.parents = map[string][]string{}
+	c.diffParents = map[string][]string{}
+
+	withParents := false
+	for _, entry := range c.entries {
+		if len(entry.parents) > 0 {
+			withParents = true
+		}
+	}
+
+	dateOrder := c.dateOrder()
+	for n, entry := range dateOrder {
+		ids := entry.parents
+		switch {
+		case len(ids) > 0:
+		case len(entry.mergeParents) > 0:
+			ids = entry.mergeParents
+		case withParents == false && n > 0:
+			ids = []string{dateOrder[n-1].id}
+		}
+
+		for _, id := range ids {
+			parentId := c.lookup(id)
+			if parentId != "" {
+				c.parents[entry.id] = append(c.parents[entry.id], parentId)
+			} else {
+				parentId = id
+			}
+			c.diffParents[entry.id] = append(c.diffParents[entry.id], parentId)
+		}
+	}
+}
+
+//creates the commits reachable from the selected revision and links them
+func (c *GitLogConnector) fetchAll() error {
+
+	parents, diffParents := c.parents, c.diffParents
+
+	_, revision := splitRange(Revision)
+	headId, err := c.Resolve(revision)
+	if err != nil {
+		return err
+	}
+
+	selected := map[string]bool{}
+	stack := []string{headId}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if selected[id] == false {
+			selected[id] = true
+			stack = append(stack, parents[id]...)
+		}
+	}
+
+	entries := map[string]*gitLogEntry{}
+	for _, entry := range c.entries {
+		if selected[entry.id] {
+			entries[entry.id] = entry
+			c.createCommit(entry)
+			c.commits[entry.id].Boundary = len(parents[entry.id]) < len(diffParents[entry.id])
+		}
+	}
+	for id := range entries {
+		for _, parentId := range parents[id] {
+			c.commits[id].Parents[parentId] = c.commits[parentId]
+			c.commits[parentId].Children[id] = c.commits[id]
+		}
+	}
+
+	//without a summary a file is added, if it is changed the first time
+	withSummary := false
+	for _, entry := range entries {
+		for _, changes := range entry.changes {
+			for _, change := range changes.changes {
+				withSummary = withSummary || change.created || change.deleted
+			}
+		}
+	}
+
+	known := map[string]bool{}
+	for _, commit := range topologicalOrder(c.commits) {
+		entry := entries[commit.Id]
+		for n, parentId := range diffParents[commit.Id] {
+			changes := entry.changes[parentId]
+			if changes == nil && n == 0 {
+				changes = entry.changes[""]
+			}
+			c.addDiffToCommit(commit, parentId, changes, known, withSummary)
+		}
+		if len(diffParents[commit.Id]) == 0 {
+			c.addDiffToCommit(commit, "", entry.changes[""], known, withSummary)
+		}
+	}
+
+	return nil
+}
+
+//creates an internal commit object based on an entry of the export
+func (c *GitLogConnector) createCommit(entry *gitLogEntry) *Commit {
+
+	dev := developerFor(c.developers, entry.authorEmail, entry.authorEmail, entry.authorName)
+	message := strings.TrimSpace(strings.Join(entry.message, "\n")) + "\n"
+	commit := NewCommit(entry.id, message, entry.date, dev)
+
+	c.commits[commit.Id] = commit
+	dev.Commits[commit.Id] = commit
+
+	//the committer is only part of some formats (e.g. fuller)
+	if entry.committerEmail != "" {
+		commit.SetCommitter(developerFor(c.developers, entry.committerEmail, entry.committerEmail, entry.committerName))
+	} else {
+		commit.SetCommitter(dev)
+	}
+	commit.readCoAuthors(c.developers)
+
+	return commit
+}
+
+//adds the changes compared to a parent (empty for root commits) to the commit
+func (c *GitLogConnector) addDiffToCommit(commit *Commit, parentId string, changes *gitLogChanges, known map[string]bool, withSummary bool) {
+
+	diff := NewParentDiff(parentId)
+	if changes == nil {
+		commit.AddDiff(diff)
+		return
+	}
+
+	for _, path := range changes.paths {
+		change := changes.changes[path]
+		if ValidPath(path) == false {
+			continue
+		}
+
+		//only binary files are detected, the contents are not available
+		file := c.loadFile(commit.Id, path)
+		if change.binary {
+			c.reportBinary(path)
+		}
+
+		switch {
+		case change.deleted:
+			diff.RemovedFiles[path] = file
+			delete(known, path)
+			diff.AddLines(path, change.lines)
+			continue
+
+		case change.oldPath != "":
+			diff.MovedFiles[change.oldPath] = path
+			delete(known, change.oldPath)
+			if change.lines.IsEmpty() == false {
+				diff.ChangedFiles[path] = file
+			}
+
+		case change.created, withSummary == false && known[path] == false && commit.Boundary == false:
+			diff.AddedFiles[path] = file
+
+		default:
+			diff.ChangedFiles[path] = file
+		}
+
+		known[path] = true
+		commit.Files[path] = file
+		diff.AddLines(path, change.lines)
+	}
+
+	commit.AddDiff(diff)
+}
+
+//creates a file object for a path within a commit, its content is not available
+func (c *GitLogConnector) loadFile(commitId string, path string) *File {
+	id := commitId + ":" + path
+	if file, exists := c.files[id]; exists {
+		return file
+	}
+	file := newFile(id, 0, id, c.fileLoader(id, id))
+	c.files[id] = file
+	return file
+}
+
+//returns the id of a commit of the export by its (abbreviated) id, empty if it is unknown or ambiguous
+func (c *GitLogConnector) lookup(prefix string) string {
+	found := ""
+	for _, entry := range c.entries {
+		if strings.HasPrefix(entry.id, prefix) {
+			if found != "" && found != entry.id {
+				return ""
+			}
+			found = entry.id
+		}
+	}
+	return found
+}
+
+//returns the commits which are no parent of any other commit ordered by date
+func (c *GitLogConnector) heads() []*gitLogEntry {
+
+	isParent := map[string]bool{}
+	for _, ids := range c.parents {
+		for _, id := range ids {
+			isParent[id] = true
+		}
+	}
+
+	heads := []*gitLogEntry{}
+	for _, entry := range c.dateOrder() {
+		if isParent[entry.id] == false {
+			heads = append(heads, entry)
+		}
+	}
+	return heads
+}
+
+/*
+splits the value of a commit line into the commit, its parents (--parents), the
+parent of the changes (-m prints "(from parent)") and the decorations
+*/
+func parseGitLogCommitLine(value string) (id string, parents []string, from string, decorations string) {
+
+	if i := strings.Index(value, " ("); i >= 0 {
+		suffix := strings.TrimSuffix(value[i+2:], ")")
+		value = value[:i]
+		if strings.HasPrefix(suffix, "from ") {
+			from = strings.TrimPrefix(suffix, "from ")
+		} else {
+			decorations = suffix
+		}
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return "", nil, from, decorations
+	}
+	return fields[0], fields[1:], from, decorations
+}
+
+//splits "Name <email>" into name and email
+func parseGitLogPerson(value string) (name string, email string) {
+	value = strings.TrimSpace(value)
+	if match := gitLogPersonPattern.FindStringSubmatch(value); match != nil {
+		return match[1], match[2]
+	}
+	return value, value
+}
+
+//parses the date of a commit in one of the supported --date formats
+func parseGitLogDate(value string) (time.Time, error) {
+
+	for _, layout := range gitLogDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+
+	//raw format: unix timestamp and timezone
+	fields := strings.Fields(value)
+	if len(fields) == 2 {
+		if seconds, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			if zone, err := time.Parse("-0700", fields[1]); err == nil {
+				return time.Unix(seconds, 0).In(zone.Location()), nil
+			}
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown date format %q", value)
+}
+
+//splits a renamed path of numstat or summary ("old => new" or "dir/{old => new}/file") into the old and new path
+func splitGitLogRename(path string) (string, string) {
+
+	if start := strings.Index(path, "{"); start >= 0 {
+		if end := strings.Index(path[start:], "}"); end >= 0 {
+			parts := strings.SplitN(path[start+1:start+end], " => ", 2)
+			if len(parts) == 2 {
+				prefix, suffix := path[:start], path[start+end+1:]
+				join := func(part string) string {
+					joined := strings.Replace(prefix+part+suffix, "//", "/", 1)
+					return unquoteGitLogPath(strings.TrimPrefix(joined, "/"))
+				}
+				return join(parts[0]), join(parts[1])
+			}
+		}
+	}
+
+	if parts := strings.SplitN(path, " => ", 2); len(parts) == 2 {
+		return unquoteGitLogPath(parts[0]), unquoteGitLogPath(parts[1])
+	}
+
+	path = unquoteGitLogPath(path)
+	return path, path
+}
+
+//paths with special characters are quoted by git
+func unquoteGitLogPath(path string) string {
+	if strings.HasPrefix(path, "\"") {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
+		}
+	}
+	return path
+}
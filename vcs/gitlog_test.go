@@ -0,0 +1,322 @@
+package vcs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+//git log --parents --numstat --summary -M
+const gitLogTestMedium = `commit 3333333333333333333333333333333333333333 2222222222222222222222222222222222222222 (HEAD -> main, tag: v1.0)
+Author: Bob <bob@example.com>
+Date:   Tue Mar 5 10:00:00 2024 +0100
+
+    move c
+
+0	0	{src => lib}/c.go
+ rename {src => lib}/c.go (100%)
+
+commit 2222222222222222222222222222222222222222 1111111111111111111111111111111111111111
+Author: Alice <alice@example.com>
+Date:   Mon Mar 4 10:00:00 2024 +0100
+
+    change a
+
+    Co-authored-by: Bob <bob@example.com>
+
+1	1	a.txt
+3	0	src/c.go
+ create mode 100644 src/c.go
+
+commit 1111111111111111111111111111111111111111
+Author: Alice <alice@example.com>
+Date:   Sun Mar 3 10:00:00 2024 +0100
+
+    add a
+
+2	0	a.txt
+-	-	logo.png
+ create mode 100644 a.txt
+ create mode 100644 logo.png
+`
+
+//git log --pretty=fuller --parents -m --numstat --summary -M
+const gitLogTestFuller = `commit 4444444444444444444444444444444444444444 2222222222222222222222222222222222222222 3333333333333333333333333333333333333333 (from 2222222222222222222222222222222222222222)
+Merge: 2222222 3333333
+Author:     Alice <alice@example.com>
+AuthorDate: Thu Mar 7 10:00:00 2024 +0100
+Commit:     Carol <carol@example.com>
+CommitDate: Thu Mar 7 11:00:00 2024 +0100
+
+    merge branch
+
+1	0	b.txt
+ create mode 100644 b.txt
+
+commit 4444444444444444444444444444444444444444 2222222222222222222222222222222222222222 3333333333333333333333333333333333333333 (from 3333333333333333333333333333333333333333)
+Merge: 2222222 3333333
+Author:     Alice <alice@example.com>
+AuthorDate: Thu Mar 7 10:00:00 2024 +0100
+Commit:     Carol <carol@example.com>
+CommitDate: Thu Mar 7 11:00:00 2024 +0100
+
+    merge branch
+
+2	1	a.txt
+
+commit 3333333333333333333333333333333333333333 1111111111111111111111111111111111111111
+Author:     Bob <bob@example.com>
+AuthorDate: Wed Mar 6 10:00:00 2024 +0100
+Commit:     Bob <bob@example.com>
+CommitDate: Wed Mar 6 10:00:00 2024 +0100
+
+    add b
+
+1	0	b.txt
+ create mode 100644 b.txt
+
+commit 2222222222222222222222222222222222222222 1111111111111111111111111111111111111111
+Author:     Alice <alice@example.com>
+AuthorDate: Tue Mar 5 10:00:00 2024 +0100
+Commit:     Alice <alice@example.com>
+CommitDate: Tue Mar 5 10:00:00 2024 +0100
+
+    change a
+
+2	1	a.txt
+
+commit 1111111111111111111111111111111111111111
+Author:     Alice <alice@example.com>
+AuthorDate: Mon Mar 4 10:00:00 2024 +0100
+Commit:     Alice <alice@example.com>
+CommitDate: Mon Mar 4 10:00:00 2024 +0100
+
+    add a
+
+1	0	a.txt
+ create mode 100644 a.txt
+`
+
+//git log --date=iso-strict --format='%H %P%n%an <%ae>%n%ad%n%B' --numstat --summary -M
+const gitLogTestFormat = `2222222222222222222222222222222222222222 1111111111111111111111111111111111111111
+Alice <alice@example.com>
+2024-03-04T10:00:00+01:00
+change a
+
+    indented line
+Author: not a header
+
+1	1	a.txt
+0	0	b.txt => c.txt
+ rename b.txt => c.txt (100%)
+
+1111111111111111111111111111111111111111
+Alice <alice@example.com>
+2024-03-03T10:00:00+01:00
+add a and b
+
+1	0	a.txt
+1	0	b.txt
+ create mode 100644 a.txt
+ create mode 100644 b.txt
+`
+
+//writes the export to a file and loads it
+func loadTestGitLog(t *testing.T, export string) (*GitLogConnector, error) {
+
+	Filter = PassThroughFilter{}
+	path := filepath.Join(t.TempDir(), "export.log")
+	if err := os.WriteFile(path, []byte(export), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &GitLogConnector{}
+	return c, c.LoadLocal(path, t.TempDir())
+}
+
+func TestGitLogMedium(t *testing.T) {
+
+	c, err := loadTestGitLog(t, gitLogTestMedium)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits := c.Commits()
+	if len(commits) != 3 {
+		t.Fatalf("expected 3 commits, got %d", len(commits))
+	}
+	root := commits["1111111111111111111111111111111111111111"]
+	change := commits["2222222222222222222222222222222222222222"]
+	move := commits["3333333333333333333333333333333333333333"]
+
+	if len(root.AddedFiles) != 2 || root.LineDiff != (LineDiff{2, 0}) {
+		t.Errorf("expected 2 added files and lines, got %v %v", root.AddedFiles, root.LineDiff)
+	}
+	if excluded := c.excludedFiles(); len(excluded) != 1 || excluded["logo.png"] != ExcludedBinary {
+		t.Errorf("expected logo.png to be reported as binary, got %v", excluded)
+	}
+
+	if change.ChangedFiles["a.txt"] == nil || change.AddedFiles["src/c.go"] == nil || change.LineDiff != (LineDiff{4, 1}) {
+		t.Errorf("unexpected changes %v %v %v", change.ChangedFiles, change.AddedFiles, change.LineDiff)
+	}
+	if change.Message != "change a\n\nCo-authored-by: Bob <bob@example.com>\n" {
+		t.Errorf("unexpected message %q", change.Message)
+	}
+	if len(change.CoAuthors) != 1 || change.CoAuthors[0] != c.Developers()["bob@example.com"] {
+		t.Errorf("expected bob as co-author, got %v", change.CoAuthors)
+	}
+
+	if len(move.MovedFiles) != 1 || move.MovedFiles["src/c.go"] != "lib/c.go" || len(move.ChangedFiles) > 0 {
+		t.Errorf("expected src/c.go to be moved to lib/c.go, got %v %v", move.MovedFiles, move.ChangedFiles)
+	}
+	if move.Developer.Name != "Bob" || move.Date.Equal(time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)) == false {
+		t.Errorf("unexpected author %v or date %v", move.Developer, move.Date)
+	}
+
+	if root.Children[change.Id] != change || change.Parents[root.Id] != root || move.Parents[change.Id] != change {
+		t.Error("commits are not linked to their parents")
+	}
+
+	if id, err := c.Resolve("v1.0"); err != nil || id != move.Id {
+		t.Errorf("expected tag v1.0 to resolve to %s, got %s (%v)", move.Id, id, err)
+	}
+	if id, err := c.Resolve("main"); err != nil || id != move.Id {
+		t.Errorf("expected branch main to resolve to %s, got %s (%v)", move.Id, id, err)
+	}
+	if id, err := c.Resolve("2222222"); err != nil || id != change.Id {
+		t.Errorf("expected the abbreviated id to resolve to %s, got %s (%v)", change.Id, id, err)
+	}
+}
+
+func TestGitLogFullerMerge(t *testing.T) {
+
+	c, err := loadTestGitLog(t, gitLogTestFuller)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits := c.Commits()
+	if len(commits) != 4 {
+		t.Fatalf("expected 4 commits, got %d", len(commits))
+	}
+	merge := commits["4444444444444444444444444444444444444444"]
+
+	if len(merge.Parents) != 2 || len(merge.Diffs) != 2 {
+		t.Fatalf("expected a merge with 2 parents, got %d parents and %d diffs", len(merge.Parents), len(merge.Diffs))
+	}
+	expected := map[string]LineDiff{
+		"2222222222222222222222222222222222222222": {1, 0},
+		"3333333333333333333333333333333333333333": {2, 1},
+	}
+	for _, diff := range merge.Diffs {
+		if diff.LineDiff != expected[diff.Parent] {
+			t.Errorf("expected %v lines compared to %s, got %v", expected[diff.Parent], diff.Parent, diff.LineDiff)
+		}
+	}
+
+	if merge.Message != "merge branch\n" {
+		t.Errorf("expected the message only once, got %q", merge.Message)
+	}
+	if merge.Developer.Name != "Alice" || merge.Committer == nil || merge.Committer.Id != "carol@example.com" {
+		t.Errorf("unexpected author %v or committer %v", merge.Developer, merge.Committer)
+	}
+	if merge.Date.Equal(time.Date(2024, 3, 7, 9, 0, 0, 0, time.UTC)) == false {
+		t.Errorf("expected the author date, got %v", merge.Date)
+	}
+}
+
+func TestGitLogFormat(t *testing.T) {
+
+	c, err := loadTestGitLog(t, gitLogTestFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits := c.Commits()
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(commits))
+	}
+	root := commits["1111111111111111111111111111111111111111"]
+	change := commits["2222222222222222222222222222222222222222"]
+
+	if len(root.AddedFiles) != 2 || root.LineDiff != (LineDiff{2, 0}) {
+		t.Errorf("expected 2 added files and lines, got %v %v", root.AddedFiles, root.LineDiff)
+	}
+	if change.MovedFiles["b.txt"] != "c.txt" || change.ChangedFiles["a.txt"] == nil || change.LineDiff != (LineDiff{1, 1}) {
+		t.Errorf("unexpected changes %v %v %v", change.MovedFiles, change.ChangedFiles, change.LineDiff)
+	}
+	if change.Message != "change a\n\n    indented line\nAuthor: not a header\n" {
+		t.Errorf("unexpected message %q", change.Message)
+	}
+	if change.Developer.Id != "alice@example.com" || change.Parents[root.Id] != root {
+		t.Errorf("unexpected author %v or parents %v", change.Developer, change.Parents)
+	}
+	if change.Date.Equal(time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)) == false {
+		t.Errorf("unexpected date %v", change.Date)
+	}
+}
+
+func TestGitLogWithoutNumstat(t *testing.T) {
+
+	exports := map[string]string{
+		"stat": `commit 1111111111111111111111111111111111111111
+Author: Alice <alice@example.com>
+Date:   Sun Mar 3 10:00:00 2024 +0100
+
+    add a
+
+ a.txt | 2 ++
+ 1 file changed, 2 insertions(+)
+`,
+		"oneline": `1111111 add a
+`,
+	}
+
+	for name, export := range exports {
+		if _, err := loadTestGitLog(t, export); err == nil {
+			t.Errorf("expected an error for the %s export", name)
+		}
+	}
+}
+
+func TestGitLogDates(t *testing.T) {
+
+	expected := time.Date(2024, 3, 3, 9, 4, 5, 0, time.UTC)
+	for _, value := range []string{
+		"Sun Mar 3 10:04:05 2024 +0100",
+		"2024-03-03 10:04:05 +0100",
+		"2024-03-03T10:04:05+01:00",
+		"Sun, 3 Mar 2024 10:04:05 +0100",
+		"1709456645 +0100",
+	} {
+		if date, err := parseGitLogDate(value); err != nil || date.Equal(expected) == false {
+			t.Errorf("expected %v for %q, got %v (%v)", expected, value, date, err)
+		}
+	}
+
+	if date, err := parseGitLogDate("2024-03-03"); err != nil || date.Format("2006-01-02") != "2024-03-03" {
+		t.Errorf("unexpected short date %v (%v)", date, err)
+	}
+	if _, err := parseGitLogDate("yesterday"); err == nil {
+		t.Error("expected an error for an unknown date format")
+	}
+}
+
+func TestGitLogRenames(t *testing.T) {
+
+	for value, expected := range map[string][2]string{
+		"a.txt":               {"a.txt", "a.txt"},
+		"a.txt => b.txt":      {"a.txt", "b.txt"},
+		"src/{a => b}/c.go":   {"src/a/c.go", "src/b/c.go"},
+		"{src => lib}/c.go":   {"src/c.go", "lib/c.go"},
+		"src/{ => lib}/c.go":  {"src/c.go", "src/lib/c.go"},
+		`"a\tb.txt" => c.txt`: {"a\tb.txt", "c.txt"},
+		"src/{a.go => b.go}":  {"src/a.go", "src/b.go"},
+	} {
+		oldPath, newPath := splitGitLogRename(value)
+		if oldPath != expected[0] || newPath != expected[1] {
+			t.Errorf("expected %v for %q, got %s and %s", expected, value, oldPath, newPath)
+		}
+	}
+}
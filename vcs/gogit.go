@@ -0,0 +1,603 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	gitdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+//rename detection with the same similarity threshold as libgit2
+var gogitDiffOptions = &object.DiffTreeOptions{
+	DetectRenames: true,
+	RenameScore:   50,
+}
+
+//git connector based on go-git, a pure go implementation of git (no cgo needed)
+type GoGitConnector struct {
+	repo        *gogit.Repository
+	storagePath string
+	commits     map[string]*Commit
+	developers  map[string]*Developer
+	files       map[string]*File
+	//commits whose parents are cut off by a shallow clone
+	boundaries map[string]bool
+	skipList
+	pathAttributes
+}
+
+func (c *GoGitConnector) LoadLocal(path string, workspace string) error {
+
+	repo, err := gogit.PlainOpen(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.init(repo, workspace); err != nil {
+		return err
+	}
+
+	log.Printf("opened local git repo in %s", path)
+	return nil
+}
+
+//clones the repository from an external source (bare)
+func (c *GoGitConnector) LoadRemote(path string, workspace string) error {
+
+	//the clone of a previous run is updated, it is only cloned again if it is missing or corrupt
+	repo, err := c.openClone(workspace)
+	if err == nil {
+		if repo, err = c.fetchRepo(repo, workspace); err != nil {
+			return err
+		}
+		log.Printf("fetched remote git repo from %s to %s", path, workspace)
+	} else {
+		if hasWorkspaceInfo(workspace) {
+			log.Printf("unable to reuse workspace %s: %s", workspace, err)
+		}
+
+		//clear workspace
+		os.RemoveAll(workspace)
+		if repo, err = c.cloneRepo(path, workspace); err != nil {
+			return err
+		}
+		log.Printf("cloned remote git repo from %s to %s", path, workspace)
+	}
+
+	return c.init(repo, workspace)
+}
+
+//opens the clone of a previous run within the workspace
+func (c *GoGitConnector) openClone(workspace string) (*gogit.Repository, error) {
+
+	if hasWorkspaceInfo(workspace) == false {
+		return nil, fmt.Errorf("no clone in %s", workspace)
+	}
+	repo, err := gogit.PlainOpen(workspace)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := repo.CommitObject(head.Hash()); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+//fetches new commits into the clone of a previous run, shallow clones are fetched by the git client
+func (c *GoGitConnector) fetchRepo(repo *gogit.Repository, workspace string) (*gogit.Repository, error) {
+
+	if isShallowClone() {
+		if err := fetchClone(workspace); err != nil {
+			return nil, err
+		}
+		//the objects fetched by the git client are not known to the opened repository
+		return gogit.PlainOpen(workspace)
+	}
+
+	refSpecs := []config.RefSpec{}
+	for _, refSpec := range cloneRefSpecs {
+		refSpecs = append(refSpecs, config.RefSpec(refSpec))
+	}
+	err := repo.Fetch(&gogit.FetchOptions{RemoteName: "origin", RefSpecs: refSpecs, Force: true, Prune: true})
+	if err != nil && err != gogit.NoErrAlreadyUpToDate {
+		return nil, fmt.Errorf("unable to update git repository: %s", err)
+	}
+	return repo, nil
+}
+
+//clones the repository (bare), go-git only supports shallow clones by depth, otherwise the git client is used
+func (c *GoGitConnector) cloneRepo(path string, workspace string) (*gogit.Repository, error) {
+
+	if CloneSince.IsZero() == false {
+		if err := cloneShallow(path, workspace); err != nil {
+			return nil, err
+		}
+		return gogit.PlainOpen(workspace)
+	}
+
+	repo, err := gogit.PlainClone(workspace, true, &gogit.CloneOptions{URL: path, Depth: CloneDepth})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get git repository: %s", err)
+	}
+	return repo, nil
+}
+
+func (c *GoGitConnector) init(repo *gogit.Repository, workspace string) error {
+
+	c.repo = repo
+	c.storagePath = workspace
+
+	shallow, err := repo.Storer.Shallow()
+	if err != nil {
+		return err
+	}
+	c.boundaries = map[string]bool{}
+	for _, hash := range shallow {
+		c.boundaries[hash.String()] = true
+	}
+
+	var fm os.FileMode = 0700
+	if err := os.MkdirAll(c.storagePath, fm); err != nil {
+		return &WorkspaceError{c.storagePath, err}
+	}
+
+	//the model could already be restored from a snapshot
+	if c.commits == nil {
+		c.developers = map[string]*Developer{}
+		c.commits = map[string]*Commit{}
+		c.files = map[string]*File{}
+	}
+
+	c.loadPathAttributes(c)
+
+	if err := c.fetchAll(); err != nil {
+		return err
+	}
+
+	log.Printf("loaded %d commits, %d delevopers and %d different files from git repo",
+		len(c.commits), len(c.developers), len(c.files))
+
+	return nil
+}
+
+func (c *GoGitConnector) Developers() map[string]*Developer {
+	return c.developers
+}
+
+func (c *GoGitConnector) Commits() map[string]*Commit {
+	return c.commits
+}
+
+func (c *GoGitConnector) resume(commits map[string]*Commit, developers map[string]*Developer, files map[string]*File) {
+	c.commits = commits
+	c.developers = developers
+	c.files = files
+}
+
+//loads all commits reachable from the selected revision
+func (c *GoGitConnector) fetchAll() error {
+
+	_, revision := splitRange(Revision)
+	headCommit, err := c.resolve(revision)
+	if err != nil {
+		return err
+	}
+
+	//already known from a snapshot
+	if _, exists := c.commits[headCommit.Hash.String()]; exists {
+		return nil
+	}
+
+	//parent commits are created recursively
+	_, err = c.createCommit(headCommit)
+	return err
+}
+
+func (c *GoGitConnector) Resolve(revision string) (string, error) {
+	commit, err := c.resolve(revision)
+	if err != nil {
+		return "", err
+	}
+	return commit.Hash.String(), nil
+}
+
+//returns the tags of all loaded commits, tags of other objects (e.g. trees) are ignored
+func (c *GoGitConnector) loadTags() ([]*Tag, error) {
+
+	refs, err := c.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []*Tag{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		hash := ref.Hash()
+		var date time.Time
+
+		//annotated tags have an own object
+		if tagObject, err := c.repo.TagObject(hash); err == nil {
+			tagCommit, err := tagObject.Commit()
+			if err != nil {
+				return nil
+			}
+			hash = tagCommit.Hash
+			date = tagObject.Tagger.When
+		}
+
+		if commit, exists := c.commits[hash.String()]; exists {
+			if date.IsZero() {
+				date = commit.Date
+			}
+			tags = append(tags, &Tag{ref.Name().Short(), commit, date})
+		}
+		return nil
+	})
+
+	return tags, err
+}
+
+//reads a file of the analyzed revision
+func (c *GoGitConnector) ReadFile(path string) ([]byte, error) {
+	_, revision := splitRange(Revision)
+	commit, err := c.resolve(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := commit.File(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := file.Contents()
+	return []byte(content), err
+}
+
+//looks up the commit of a branch, tag or commit id (HEAD if empty)
+func (c *GoGitConnector) resolve(revision string) (*object.Commit, error) {
+
+	if revision == "" {
+		revision = "HEAD"
+	}
+
+	hash, err := c.repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve revision %s: %s", revision, err)
+	}
+
+	return c.repo.CommitObject(*hash)
+}
+
+/*
+creates an internal commit object based on the go-git commit
+recursively create objects for parent commits
+*/
+func (c *GoGitConnector) createCommit(gitCommit *object.Commit) (*Commit, error) {
+	author := gitCommit.Author
+
+	dev, exists := c.developers[author.Email]
+	if !exists {
+		dev = NewDeveloper(author.Email, author.Email, author.Name)
+		c.developers[author.Email] = dev
+	}
+
+	commit := NewCommit(gitCommit.Hash.String(), gitCommit.Message, author.When, dev)
+
+	c.commits[commit.Id] = commit
+	dev.Commits[commit.Id] = commit
+
+	committer := gitCommit.Committer
+	commit.SetCommitter(developerFor(c.developers, committer.Email, committer.Email, committer.Name))
+	commit.readCoAuthors(c.developers)
+
+	//commits with a corrupt tree are kept, but without changes
+	tree, err := gitCommit.Tree()
+	if err != nil {
+		c.skip(&DiffError{Commit: commit.Id, Err: err})
+	}
+
+	//the parents of a boundary commit are missing, it is loaded like a root commit
+	if c.boundaries[commit.Id] {
+		commit.Boundary = true
+		c.loadTreeDiffToCommit(commit, "", &object.Tree{}, tree)
+		return commit, nil
+	}
+
+	if gitCommit.NumParents() == 0 {
+		c.loadTreeDiffToCommit(commit, "", &object.Tree{}, tree)
+	}
+	if gitCommit.NumParents() > 1 && tree != nil {
+		c.detectSubtrees(commit, gitCommit, tree)
+	}
+
+	//iterate over parent commits and create or reference them
+	for _, parentHash := range gitCommit.ParentHashes {
+		if commit.skipsSubtree(parentHash.String()) {
+			continue
+		}
+
+		parentGitCommit, err := c.repo.CommitObject(parentHash)
+		if err != nil {
+			return nil, err
+		}
+
+		var parentCommit *Commit
+		if parentCommit, exists = c.commits[parentHash.String()]; !exists {
+			if parentCommit, err = c.createCommit(parentGitCommit); err != nil {
+				return nil, err
+			}
+		}
+		commit.Parents[parentCommit.Id] = parentCommit
+		parentCommit.Children[commit.Id] = commit
+
+		parentTree, err := parentGitCommit.Tree()
+		if err != nil && tree != nil {
+			c.skip(&DiffError{Commit: commit.Id, Parent: parentCommit.Id, Err: err})
+		}
+		c.loadTreeDiffToCommit(commit, parentCommit.Id, parentTree, tree)
+	}
+
+	return commit, nil
+}
+
+//detects the parents (except the first one) which were merged as subtree
+func (c *GoGitConnector) detectSubtrees(commit *Commit, gitCommit *object.Commit, tree *object.Tree) {
+
+	var dirs map[string]treeDir
+	for _, parentHash := range gitCommit.ParentHashes[1:] {
+		parentGitCommit, err := c.repo.CommitObject(parentHash)
+		if err != nil {
+			continue
+		}
+		parentTree, err := parentGitCommit.Tree()
+		if err != nil {
+			continue
+		}
+
+		if dirs == nil {
+			dirs = gogitTreeDirs(tree)
+		}
+		parent := treeDir{parentTree.Hash.String(), map[string]string{}}
+		for _, entry := range parentTree.Entries {
+			parent.entries[entry.Name] = entry.Hash.String()
+		}
+
+		if prefix := subtreePrefix(dirs, parent); prefix != "" {
+			log.Printf("commit %s merges %s as subtree %s", commit.Id, parentHash, prefix)
+			commit.Subtrees[parentHash.String()] = prefix
+		}
+	}
+}
+
+//returns all directories of a tree by path (the root directory is empty)
+func gogitTreeDirs(tree *object.Tree) map[string]treeDir {
+
+	dirs := map[string]treeDir{"": {tree.Hash.String(), map[string]string{}}}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		name, entry, err := walker.Next()
+		if err != nil {
+			break
+		}
+		if entry.Mode == filemode.Dir {
+			dirs[name] = treeDir{entry.Hash.String(), map[string]string{}}
+		}
+
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		if parent, exists := dirs[dir]; exists {
+			parent.entries[entry.Name] = entry.Hash.String()
+		}
+	}
+	return dirs
+}
+
+//returns the commits of all submodules (gitlinks) of the analyzed revision by path
+func (c *GoGitConnector) gitlinks() (map[string]string, error) {
+
+	_, revision := splitRange(Revision)
+	commit, err := c.resolve(revision)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	gitlinks := map[string]string{}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if entry.Mode == filemode.Submodule {
+			gitlinks[name] = entry.Hash.String()
+		}
+	}
+	return gitlinks, nil
+}
+
+/*
+adds the changes between the commit and one of its parents (empty for root
+commits), changes which cannot be computed are skipped, the diff is empty if
+one of the trees is missing, submodules (gitlinks) are no files and ignored
+*/
+func (c *GoGitConnector) loadTreeDiffToCommit(commit *Commit, parentId string, parentTree *object.Tree, newTree *object.Tree) {
+
+	diff := NewParentDiff(parentId)
+	defer commit.AddDiff(diff)
+
+	if parentTree == nil || newTree == nil {
+		return
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), parentTree, newTree, gogitDiffOptions)
+	if err != nil {
+		c.skip(&DiffError{Commit: commit.Id, Parent: parentId, Err: err})
+		return
+	}
+
+	for _, change := range changes {
+		filepath := change.To.Name
+		oldFilepath := change.From.Name
+		if filepath == "" {
+			filepath = oldFilepath
+		}
+
+		if c.validPath(filepath) == false ||
+			change.From.TreeEntry.Mode == filemode.Submodule || change.To.TreeEntry.Mode == filemode.Submodule {
+			continue
+		}
+
+		action, err := change.Action()
+		if err != nil {
+			c.skip(&DiffError{commit.Id, parentId, filepath, err})
+			continue
+		}
+
+		var file, oldFile *File
+		if oldFilepath != "" {
+			oldFile = c.loadFile(change.From.TreeEntry.Hash)
+		}
+		if action != merkletrie.Delete {
+			file = c.loadFile(change.To.TreeEntry.Hash)
+			commit.Files[filepath] = file
+		}
+
+		switch {
+		case action == merkletrie.Insert:
+			diff.AddedFiles[filepath] = file
+		case action == merkletrie.Delete:
+			diff.RemovedFiles[oldFilepath] = oldFile
+		case oldFilepath != filepath:
+			diff.MovedFiles[oldFilepath] = filepath
+			if file.Id != oldFile.Id {
+				diff.ChangedFiles[filepath] = file
+				file.Parents = append(file.Parents, oldFile)
+			}
+		default:
+			diff.ChangedFiles[filepath] = file
+			file.Parents = append(file.Parents, oldFile)
+		}
+
+		//count changed lines
+		patch, err := change.Patch()
+		if err != nil {
+			c.skip(&DiffError{commit.Id, parentId, filepath, err})
+			continue
+		}
+		for _, stat := range patch.Stats() {
+			diff.AddLines(filepath, LineDiff{stat.Addition, stat.Deletion})
+		}
+		if KeepHunks {
+			for _, hunk := range gogitHunks(filepath, oldFilepath, patch) {
+				diff.AddHunk(hunk)
+			}
+		}
+	}
+}
+
+//converts the chunks of a patch (complete files) into hunks, binary files have no hunks
+func gogitHunks(path string, oldPath string, patch *object.Patch) []*Hunk {
+
+	hunks := []*Hunk{}
+	for _, filePatch := range patch.FilePatches() {
+		if filePatch.IsBinary() {
+			continue
+		}
+
+		lines := []HunkLine{}
+		for _, chunk := range filePatch.Chunks() {
+			var origin byte = LineContext
+			switch chunk.Type() {
+			case gitdiff.Add:
+				origin = LineAdded
+			case gitdiff.Delete:
+				origin = LineRemoved
+			}
+			for _, content := range strings.SplitAfter(chunk.Content(), "\n") {
+				if content != "" {
+					lines = append(lines, HunkLine{Origin: origin, Content: content})
+				}
+			}
+		}
+		hunks = append(hunks, splitHunks(path, oldPath, lines)...)
+	}
+	return hunks
+}
+
+/*
+creates a file object for a blob, the content is loaded on demand, missing or
+corrupt blobs are skipped: the file is created without size and its content
+returns a BlobError
+*/
+func (c *GoGitConnector) loadFile(hash plumbing.Hash) *File {
+
+	if file, exists := c.files[hash.String()]; exists {
+		return file
+	}
+
+	var size int64
+	if blob, err := c.repo.BlobObject(hash); err != nil {
+		c.skip(missingBlob(hash.String(), err))
+	} else {
+		size = blob.Size
+	}
+
+	file := newFile(hash.String(), size, "", c.fileLoader(hash.String(), ""))
+	c.files[hash.String()] = file
+	return file
+}
+
+//blobs are loaded by their id, no further source is needed
+func (c *GoGitConnector) fileLoader(id string, source string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		content, err := c.readBlob(plumbing.NewHash(id))
+		if err != nil {
+			return nil, missingBlob(id, err)
+		}
+		return content, nil
+	}
+}
+
+func (c *GoGitConnector) readBlob(hash plumbing.Hash) ([]byte, error) {
+
+	blob, err := c.repo.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
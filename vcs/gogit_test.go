@@ -0,0 +1,168 @@
+package vcs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+loads a local git repository with an added, changed, moved, removed and moved
+and changed file, a merge of a branch and a subtree merge of another history
+*/
+func TestGoGitConnector(t *testing.T) {
+
+	Filter = PassThroughFilter{}
+	GitBackend = GoGit
+	KeepHunks = true
+	ImportPolicy = ImportSkip
+	WorkspaceRoot = t.TempDir()
+	defer func() { GitBackend, KeepHunks, ImportPolicy, WorkspaceRoot = "", false, ImportInclude, "" }()
+
+	repoPath := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Alice", "GIT_AUTHOR_EMAIL=alice@example.com",
+			"GIT_COMMITTER_NAME=Bob", "GIT_COMMITTER_EMAIL=bob@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %s", args, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(name string, content string) {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(repoPath, name)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(message string) {
+		git("add", "-A")
+		git("commit", "-q", "-m", message)
+	}
+
+	long := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n"
+	git("init", "-q", "-b", "main")
+	write("a.txt", "one\ntwo\n")
+	write("b.txt", "b\n")
+	write("e.txt", long)
+	commit("add a, b and e")
+
+	write("a.txt", "one\ntwo\nthree\n")
+	commit("change a")
+
+	git("checkout", "-q", "-b", "feature")
+	write("d.txt", "d\n")
+	commit("add d")
+	git("checkout", "-q", "main")
+
+	git("mv", "b.txt", "c.txt")
+	commit("move b")
+
+	git("mv", "e.txt", "f.txt")
+	write("f.txt", long+"nine\n")
+	commit("move and change e")
+
+	git("rm", "-q", "a.txt")
+	commit("remove a")
+
+	git("merge", "-q", "--no-ff", "-m", "merge feature", "feature")
+
+	git("checkout", "-q", "--orphan", "lib")
+	git("rm", "-q", "-r", "-f", ".")
+	write("lib.txt", "lib\n")
+	commit("add lib")
+	lib := git("rev-parse", "HEAD")
+	git("checkout", "-q", "main")
+	git("merge", "-q", "-s", "ours", "--no-commit", "--allow-unrelated-histories", "lib")
+	git("read-tree", "--prefix=lib/", "-u", "lib")
+	git("commit", "-q", "-m", "import lib")
+	git("branch", "-q", "-D", "lib")
+
+	repo, err := NewRepository(repoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Skipped) > 0 {
+		t.Fatalf("unexpected skipped objects %v", repo.Skipped)
+	}
+
+	commits := map[string]*Commit{}
+	for _, commit := range repo.Commits {
+		commits[commit.Message] = commit
+	}
+	if len(commits) != 8 {
+		t.Fatalf("expected 8 commits, got %v", commits)
+	}
+
+	add, change, branch, move, moveChange, remove, merge, subtree := commits["add a, b and e\n"], commits["change a\n"],
+		commits["add d\n"], commits["move b\n"], commits["move and change e\n"], commits["remove a\n"], commits["merge feature\n"], commits["import lib\n"]
+
+	if len(add.AddedFiles) != 3 || add.LineDiff != (LineDiff{11, 0}) {
+		t.Errorf("expected 3 files with 11 lines to be added, got %v %v", add.AddedFiles, add.LineDiff)
+	}
+	if add.Developer.Id != "alice@example.com" || add.Developer.Name != "Alice" || add.Committer.Id != "bob@example.com" {
+		t.Errorf("expected alice as author and bob as committer, got %v and %v", add.Developer, add.Committer)
+	}
+
+	if file := change.ChangedFiles["a.txt"]; len(change.ChangedFiles) != 1 || file == nil || len(file.Parents) != 1 || file.Parents[0] != add.Files["a.txt"] {
+		t.Errorf("expected a.txt to be changed based on the added version, got %v", change.ChangedFiles)
+	}
+	if change.LineDiff != (LineDiff{1, 0}) {
+		t.Errorf("expected 1 added line, got %v", change.LineDiff)
+	}
+	if hunks := change.Diffs[0].Hunks["a.txt"]; len(hunks) != 1 || hunks[0].OldStart != 1 || hunks[0].OldLines != 2 ||
+		hunks[0].NewStart != 1 || hunks[0].NewLines != 3 || len(hunks[0].Added()) != 1 || hunks[0].Added()[0].NewLine != 3 {
+		t.Errorf("expected a hunk adding line 3 of a.txt, got %v", hunks)
+	}
+
+	if len(move.MovedFiles) != 1 || move.MovedFiles["b.txt"] != "c.txt" {
+		t.Errorf("expected b.txt to be moved to c.txt, got %v", move.MovedFiles)
+	}
+	if len(move.AddedFiles) > 0 || len(move.ChangedFiles) > 0 || len(move.RemovedFiles) > 0 || move.LineDiff != (LineDiff{}) {
+		t.Errorf("expected only a move, got %v %v %v %v", move.AddedFiles, move.ChangedFiles, move.RemovedFiles, move.LineDiff)
+	}
+
+	if file := moveChange.ChangedFiles["f.txt"]; moveChange.MovedFiles["e.txt"] != "f.txt" || file == nil || file.Parents[0] != add.Files["e.txt"] {
+		t.Errorf("expected e.txt to be moved and changed, got %v %v", moveChange.MovedFiles, moveChange.ChangedFiles)
+	}
+	if hunks := moveChange.Diffs[0].Hunks["f.txt"]; moveChange.LineDiff != (LineDiff{1, 0}) || len(hunks) != 1 || hunks[0].OldPath != "e.txt" {
+		t.Errorf("expected 1 added line of f.txt, got %v %v", moveChange.LineDiff, hunks)
+	}
+
+	if len(remove.RemovedFiles) != 1 || remove.RemovedFiles["a.txt"] != change.Files["a.txt"] || remove.LineDiff != (LineDiff{0, 3}) {
+		t.Errorf("expected the 3 lines of a.txt to be removed, got %v %v", remove.RemovedFiles, remove.LineDiff)
+	}
+
+	//parent and child links
+	if len(add.Parents) > 0 {
+		t.Errorf("expected the first commit to be the root, got parents %v", add.Parents)
+	}
+	links := [][2]*Commit{{add, change}, {change, branch}, {change, move}, {move, moveChange}, {moveChange, remove},
+		{remove, merge}, {branch, merge}, {merge, subtree}}
+	for _, link := range links {
+		parent, child := link[0], link[1]
+		if child.Parents[parent.Id] != parent || parent.Children[child.Id] != child {
+			t.Errorf("expected %q to be a parent of %q", parent.Message, child.Message)
+		}
+	}
+	if len(merge.Parents) != 2 || len(merge.Diffs) != 2 || merge.OrderedParents()[0] != remove {
+		t.Errorf("expected a merge of two branches, got %d parents and %d diffs", len(merge.Parents), len(merge.Diffs))
+	}
+
+	//the imported history is skipped, its files exist within the subtree
+	if subtree.Subtrees[lib] != "lib" || len(subtree.Parents) != 1 || len(subtree.Diffs) != 1 {
+		t.Fatalf("expected a subtree merge of lib, got %v with %d parents", subtree.Subtrees, len(subtree.Parents))
+	}
+	if subtree.Diffs[0].ExistingFiles["lib/lib.txt"] == nil || len(subtree.AddedFiles) > 0 || subtree.LineDiff != (LineDiff{}) {
+		t.Errorf("expected lib/lib.txt to be imported, got %v %v", subtree.AddedFiles, subtree.LineDiff)
+	}
+	if head := repo.Head(); head != subtree {
+		t.Errorf("expected the subtree merge as head, got %v", head)
+	}
+}
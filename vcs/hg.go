@@ -0,0 +1,417 @@
+package vcs
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	hgNullId = "0000000000000000000000000000000000000000"
+
+	//fields are separated by \x1f (unit separator), commits by \x1e (record separator)
+	hgLogTemplate = "{node}\\x1f{p1node}\\x1f{p2node}\\x1f{author|email}\\x1f{author|person}\\x1f{date|rfc3339date}\\x1f{desc}\\x1e"
+)
+
+//connector for mercurial repositories, based on the hg command line client
+type HgConnector struct {
+	repoPath    string
+	storagePath string
+	commits     map[string]*Commit
+	developers  map[string]*Developer
+	files       map[string]*File
+	skipList
+	pathAttributes
+}
+
+//single line of "hg status -C"
+type hgStatus struct {
+	Status string
+	Path   string
+	Source string
+}
+
+func (c *HgConnector) LoadLocal(path string, workspace string) error {
+
+	if err := c.init(path, workspace); err != nil {
+		return err
+	}
+
+	log.Printf("opened local hg repo in %s", path)
+	return nil
+}
+
+/*
+clones the repository (without working copy) to the workspace, the clone of a
+previous run is updated (it is only cloned again if it is missing or corrupt)
+*/
+func (c *HgConnector) LoadRemote(path string, workspace string) error {
+
+	_, err := c.hg("--repository", workspace, "root")
+	if hasWorkspaceInfo(workspace) && err == nil {
+		if _, err := c.hg("--repository", workspace, "pull", "--quiet", path); err != nil {
+			return fmt.Errorf("unable to update hg repository: %s", err)
+		}
+		log.Printf("pulled remote hg repo from %s to %s", path, workspace)
+	} else {
+		//clear workspace
+		os.RemoveAll(workspace)
+		if _, err := c.hg("clone", "--noupdate", path, workspace); err != nil {
+			return fmt.Errorf("unable to get hg repository: %s", err)
+		}
+		log.Printf("cloned remote hg repo from %s to %s", path, workspace)
+	}
+
+	return c.init(workspace, workspace)
+}
+
+func (c *HgConnector) init(repoPath string, workspace string) error {
+
+	c.repoPath = repoPath
+	c.storagePath = workspace
+
+	var fm os.FileMode = 0700
+	if err := os.MkdirAll(c.storagePath, fm); err != nil {
+		return &WorkspaceError{c.storagePath, err}
+	}
+
+	//the model could already be restored from a snapshot
+	if c.commits == nil {
+		c.developers = map[string]*Developer{}
+		c.commits = map[string]*Commit{}
+		c.files = map[string]*File{}
+	}
+
+	c.loadPathAttributes(c)
+
+	if err := c.fetchAll(); err != nil {
+		return err
+	}
+
+	log.Printf("loaded %d commits, %d delevopers and %d different files from hg repo",
+		len(c.commits), len(c.developers), len(c.files))
+
+	return nil
+}
+
+func (c *HgConnector) Developers() map[string]*Developer {
+	return c.developers
+}
+
+func (c *HgConnector) Commits() map[string]*Commit {
+	return c.commits
+}
+
+func (c *HgConnector) resume(commits map[string]*Commit, developers map[string]*Developer, files map[string]*File) {
+	c.commits = commits
+	c.developers = developers
+	c.files = files
+}
+
+//runs a hg command within the repository (paths are printed relative to it) without any user configuration
+func (c *HgConnector) hg(args ...string) ([]byte, error) {
+	return runCommandIn(c.repoPath, []string{"HGPLAIN=1"}, "hg", args...)
+}
+
+func (c *HgConnector) fetchAll() error {
+
+	_, revision := splitRange(Revision)
+
+	//ancestors are sorted by their local revision number, so parents are always created before their children
+	out, err := c.hg("log", "-r", "::("+hgRevision(revision)+")", "--template", hgLogTemplate)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range strings.Split(string(out), "\x1e") {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(record, "\x1f", 7)
+		if len(fields) != 7 {
+			return fmt.Errorf("unable to read hg log record %q", record)
+		}
+
+		if err := c.createCommit(fields); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *HgConnector) Resolve(revision string) (string, error) {
+	out, err := c.hg("log", "-r", hgRevision(revision), "--template", "{node}")
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve revision %s: %s", revision, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+//returns the tags of all loaded commits (without the "tip" pseudo tag), the date of a tag is the date of its commit
+func (c *HgConnector) loadTags() ([]*Tag, error) {
+
+	out, err := c.hg("log", "-r", "tag()", "--template", "{node}\\x1f{join(tags, '\\x1f')}\\x1e")
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []*Tag{}
+	for _, record := range strings.Split(string(out), "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		commit, exists := c.commits[fields[0]]
+		if exists == false {
+			continue
+		}
+		for _, name := range fields[1:] {
+			if name != "" && name != "tip" {
+				tags = append(tags, &Tag{name, commit, commit.Date})
+			}
+		}
+	}
+	return tags, nil
+}
+
+//reads a file of the analyzed revision
+func (c *HgConnector) ReadFile(path string) ([]byte, error) {
+	_, revision := splitRange(Revision)
+	return c.hg("cat", "-r", hgRevision(revision), "path:"+path)
+}
+
+//translates the git like default revision to hg
+func hgRevision(revision string) string {
+	if revision == "" || revision == "HEAD" {
+		return "tip"
+	}
+	return revision
+}
+
+//creates an internal commit object based on the fields of a log record
+func (c *HgConnector) createCommit(fields []string) error {
+
+	id, parentIds, email, name := fields[0], fields[1:3], fields[3], fields[4]
+
+	//already known from a snapshot
+	if _, exists := c.commits[id]; exists {
+		return nil
+	}
+
+	dev, exists := c.developers[email]
+	if !exists {
+		dev = NewDeveloper(email, email, name)
+		c.developers[email] = dev
+	}
+
+	date, err := time.Parse(time.RFC3339, fields[5])
+	if err != nil {
+		return fmt.Errorf("invalid date in commit %s: %s", id, err)
+	}
+
+	commit := NewCommit(id, fields[6], date, dev)
+	c.commits[id] = commit
+	dev.Commits[id] = commit
+
+	//hg does not distinguish author and committer
+	commit.SetCommitter(dev)
+	commit.readCoAuthors(c.developers)
+
+	isRoot := true
+	for _, parentId := range parentIds {
+		if parentId == hgNullId {
+			continue
+		}
+		isRoot = false
+
+		parentCommit, exists := c.commits[parentId]
+		if !exists {
+			return fmt.Errorf("missing parent %s of commit %s", parentId, id)
+		}
+		commit.Parents[parentId] = parentCommit
+		parentCommit.Children[id] = commit
+
+		c.loadDiffToCommit(commit, parentId)
+	}
+
+	if isRoot {
+		c.loadDiffToCommit(commit, "")
+	}
+	return nil
+}
+
+/*
+adds the changes between the commit and one of its parents (empty for root
+commits), files which cannot be read are skipped, the diff is empty if the
+changes cannot be computed
+*/
+func (c *HgConnector) loadDiffToCommit(commit *Commit, parentId string) {
+
+	statusArgs := []string{"status", "-C", "--change", commit.Id}
+	diffArgs := []string{"diff", "--git", "-c", commit.Id}
+	if parentId != "" {
+		statusArgs = []string{"status", "-C", "--rev", parentId, "--rev", commit.Id}
+		diffArgs = []string{"diff", "--git", "-r", parentId, "-r", commit.Id}
+	}
+
+	diff := NewParentDiff(parentId)
+	defer commit.AddDiff(diff)
+
+	out, err := c.hg(statusArgs...)
+	if err != nil {
+		c.skip(&DiffError{Commit: commit.Id, Parent: parentId, Err: err})
+		return
+	}
+	changes := parseHgStatus(string(out))
+
+	//a rename is a copy of a file that was removed within the same commit
+	removed := map[string]bool{}
+	for _, change := range changes {
+		if change.Status == "R" {
+			removed[change.Path] = true
+		}
+	}
+	moved := map[string]bool{}
+
+	for _, change := range changes {
+		if change.Status == "R" || c.validPath(change.Path) == false {
+			continue
+		}
+
+		file, err := c.loadFile(commit.Id, change.Path)
+		if err != nil {
+			c.skip(err)
+			continue
+		}
+		commit.Files[change.Path] = file
+
+		switch {
+		case change.Status == "A" && change.Source != "" && removed[change.Source]:
+			oldFile, err := c.loadFile(parentId, change.Source)
+			if err != nil {
+				c.skip(err)
+				continue
+			}
+			moved[change.Source] = true
+			diff.MovedFiles[change.Source] = change.Path
+
+			if oldFile.Id != file.Id {
+				diff.ChangedFiles[change.Path] = file
+				file.Parents = append(file.Parents, oldFile)
+			}
+
+		case change.Status == "A":
+			diff.AddedFiles[change.Path] = file
+
+		case change.Status == "M":
+			oldFile, err := c.loadFile(parentId, change.Path)
+			if err != nil {
+				c.skip(err)
+				continue
+			}
+			diff.ChangedFiles[change.Path] = file
+			file.Parents = append(file.Parents, oldFile)
+		}
+	}
+
+	for path := range removed {
+		if moved[path] == false && c.validPath(path) {
+			oldFile, err := c.loadFile(parentId, path)
+			if err != nil {
+				c.skip(err)
+				continue
+			}
+			diff.RemovedFiles[path] = oldFile
+		}
+	}
+
+	//count changed lines
+	out, err = c.hg(diffArgs...)
+	if err != nil {
+		c.skip(&DiffError{Commit: commit.Id, Parent: parentId, Err: err})
+		return
+	}
+
+	patches, err := parseUnifiedDiff(bytes.NewReader(out))
+	if err != nil {
+		c.skip(&DiffError{Commit: commit.Id, Parent: parentId, Err: err})
+		return
+	}
+
+	for _, patch := range patches {
+		if c.validPath(patch.Path()) {
+			diff.AddLines(patch.Path(), patch.LineDiff)
+			for _, hunk := range patch.Hunks {
+				diff.AddHunk(hunk)
+			}
+		}
+	}
+}
+
+//loads the content of a file at the given commit
+func (c *HgConnector) loadFile(commitId string, path string) (*File, error) {
+
+	content, err := c.hg("cat", "-r", commitId, "path:"+path)
+	if err != nil {
+		return nil, &BlobError{commitId + ":" + path, err}
+	}
+
+	id := blobId(content)
+	if file, exists := c.files[id]; exists {
+		return file, nil
+	}
+
+	//the content is needed for the id anyway, so it is cached right away
+	Blobs.Put(id, content)
+	source := commitId + ":" + path
+	file := newFile(id, int64(len(content)), source, c.fileLoader(id, source))
+	c.files[id] = file
+	return file, nil
+}
+
+//files are loaded by their commit and path ("commit:path")
+func (c *HgConnector) fileLoader(id string, source string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		i := strings.Index(source, ":")
+		if i < 0 {
+			return nil, &BlobError{id, fmt.Errorf("unknown source %q", source)}
+		}
+		content, err := c.hg("cat", "-r", source[:i], "path:"+source[i+1:])
+		if err != nil {
+			return nil, &BlobError{id, err}
+		}
+		return content, nil
+	}
+}
+
+//parses the output of "hg status -C", copy sources are assigned to the added file
+func parseHgStatus(out string) []hgStatus {
+
+	changes := []hgStatus{}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 3 {
+			continue
+		}
+
+		if line[0] == ' ' {
+			if len(changes) > 0 {
+				changes[len(changes)-1].Source = strings.TrimSpace(line)
+			}
+			continue
+		}
+
+		changes = append(changes, hgStatus{Status: line[:1], Path: line[2:]})
+	}
+
+	sort.Sort(hgStatusByPath(changes))
+	return changes
+}
+
+type hgStatusByPath []hgStatus
+
+func (s hgStatusByPath) Len() int           { return len(s) }
+func (s hgStatusByPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s hgStatusByPath) Less(i, j int) bool { return s[i].Path < s[j].Path }
@@ -0,0 +1,214 @@
+package vcs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//output of "hg status -C" for a commit that renames, adds, changes and removes files
+const hgTestStatus = `A c.txt
+  b.txt
+A d.txt
+M a.txt
+R b.txt
+R e.txt
+`
+
+//output of "hg diff --git" for the same commit
+const hgTestDiff = `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ one
+-two
++three
+diff --git a/b.txt b/c.txt
+rename from b.txt
+rename to c.txt
+diff --git a/d.txt b/d.txt
+new file mode 100644
+--- /dev/null
++++ b/d.txt
+@@ -0,0 +1,1 @@
++d
+diff --git a/e.txt b/e.txt
+deleted file mode 100644
+--- a/e.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-e
+--- e
+`
+
+func TestHgStatus(t *testing.T) {
+
+	expected := []hgStatus{
+		{"M", "a.txt", ""},
+		{"R", "b.txt", ""},
+		{"A", "c.txt", "b.txt"},
+		{"A", "d.txt", ""},
+		{"R", "e.txt", ""},
+	}
+
+	changes := parseHgStatus(hgTestStatus)
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], change)
+		}
+	}
+}
+
+func TestHgDiff(t *testing.T) {
+
+	patches, err := parseUnifiedDiff(strings.NewReader(hgTestDiff))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]LineDiff{"a.txt": {1, 1}, "c.txt": {0, 0}, "d.txt": {1, 0}, "e.txt": {0, 2}}
+	if len(patches) != len(expected) {
+		t.Fatalf("expected %d patches, got %d", len(expected), len(patches))
+	}
+	for _, patch := range patches {
+		if lines, exists := expected[patch.Path()]; exists == false || patch.LineDiff != lines {
+			t.Errorf("expected %v lines for %s, got %v", lines, patch.Path(), patch.LineDiff)
+		}
+	}
+	if patches[1].OldPath != "b.txt" {
+		t.Errorf("expected c.txt to be renamed from b.txt, got %s", patches[1].OldPath)
+	}
+}
+
+/*
+creates a repository with two branches: two files are added, one of them is
+changed, the other one is moved and the first one is removed, a file added on
+the second branch is merged at last
+*/
+func TestHgConnector(t *testing.T) {
+
+	if _, err := exec.LookPath("hg"); err != nil {
+		t.Skip("hg is not installed")
+	}
+
+	Filter = PassThroughFilter{}
+	WorkspaceRoot = t.TempDir()
+	defer func() { WorkspaceRoot = "" }()
+
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	run := func(args ...string) {
+		if _, err := runCommandIn(repoPath, []string{"HGPLAIN=1"}, "hg", args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(name string, content string) {
+		if err := os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(message string) {
+		run("commit", "-q", "-u", "Alice <alice@example.com>", "-m", message)
+	}
+
+	if err := os.MkdirAll(repoPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	run("init")
+
+	write("a.txt", "one\ntwo\n")
+	write("b.txt", "b\n")
+	run("add", "-q", "a.txt", "b.txt")
+	commit("add a and b")
+
+	write("a.txt", "one\ntwo\nthree\n")
+	commit("change a")
+
+	run("mv", "b.txt", "c.txt")
+	commit("move b")
+
+	run("rm", "a.txt")
+	commit("remove a")
+
+	run("update", "-q", "1")
+	write("d.txt", "d\n")
+	run("add", "-q", "d.txt")
+	commit("add d")
+
+	run("merge", "-q", "3")
+	commit("merge")
+
+	repo, err := NewRepository(repoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Skipped) > 0 {
+		t.Fatalf("unexpected skipped objects %v", repo.Skipped)
+	}
+
+	commits := map[string]*Commit{}
+	for _, commit := range repo.Commits {
+		commits[commit.Message] = commit
+	}
+	if len(commits) != 6 {
+		t.Fatalf("expected 6 commits, got %d", len(commits))
+	}
+
+	add, change, move, remove, branch, merge :=
+		commits["add a and b"], commits["change a"], commits["move b"], commits["remove a"], commits["add d"], commits["merge"]
+
+	if len(add.AddedFiles) != 2 || add.AddedFiles["a.txt"] == nil || add.AddedFiles["b.txt"] == nil {
+		t.Errorf("expected a.txt and b.txt to be added, got %v", add.AddedFiles)
+	}
+	if add.LineDiff != (LineDiff{3, 0}) {
+		t.Errorf("expected 3 added lines, got %v", add.LineDiff)
+	}
+	if dev := add.Developer; dev == nil || dev.Id != "alice@example.com" || dev.Name != "Alice" {
+		t.Errorf("unexpected author %v", dev)
+	}
+
+	if len(change.ChangedFiles) != 1 || change.ChangedFiles["a.txt"] == nil {
+		t.Errorf("expected a.txt to be changed, got %v", change.ChangedFiles)
+	} else if parents := change.ChangedFiles["a.txt"].Parents; len(parents) != 1 || parents[0] != add.Files["a.txt"] {
+		t.Errorf("expected the added version as parent of a.txt, got %v", parents)
+	}
+	if change.LineDiff != (LineDiff{1, 0}) {
+		t.Errorf("expected 1 added line, got %v", change.LineDiff)
+	}
+
+	if len(move.MovedFiles) != 1 || move.MovedFiles["b.txt"] != "c.txt" {
+		t.Errorf("expected b.txt to be moved to c.txt, got %v", move.MovedFiles)
+	}
+	if len(move.AddedFiles) > 0 || len(move.ChangedFiles) > 0 || len(move.RemovedFiles) > 0 || move.LineDiff != (LineDiff{}) {
+		t.Errorf("expected only a move, got %v %v %v %v", move.AddedFiles, move.ChangedFiles, move.RemovedFiles, move.LineDiff)
+	}
+	if content, err := move.Files["c.txt"].Content(); err != nil || content != "b\n" {
+		t.Errorf("unexpected content of c.txt %q (%v)", content, err)
+	}
+
+	if len(remove.RemovedFiles) != 1 || remove.RemovedFiles["a.txt"] != change.Files["a.txt"] {
+		t.Errorf("expected the changed version of a.txt to be removed, got %v", remove.RemovedFiles)
+	}
+	if remove.LineDiff != (LineDiff{0, 3}) {
+		t.Errorf("expected 3 removed lines, got %v", remove.LineDiff)
+	}
+
+	//parent and child links
+	if len(add.Parents) > 0 {
+		t.Errorf("expected the first commit to be the root, got parents %v", add.Parents)
+	}
+	links := [][2]*Commit{{add, change}, {change, move}, {move, remove}, {change, branch}, {remove, merge}, {branch, merge}}
+	for _, link := range links {
+		parent, child := link[0], link[1]
+		if child.Parents[parent.Id] != parent || parent.Children[child.Id] != child {
+			t.Errorf("expected %q to be a parent of %q", parent.Message, child.Message)
+		}
+	}
+	if len(merge.Parents) != 2 || len(merge.Diffs) != 2 || len(change.Children) != 2 {
+		t.Errorf("expected a merge of two branches, got %d parents and %d diffs", len(merge.Parents), len(merge.Diffs))
+	}
+}
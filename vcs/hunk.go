@@ -0,0 +1,330 @@
+package vcs
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//origin of a line within a hunk
+const (
+	LineContext = ' '
+	LineAdded   = '+'
+	LineRemoved = '-'
+)
+
+//number of unchanged lines around the changes of a hunk (as used by git diff)
+const hunkContext = 3
+
+//lines with less characters (e.g. "}") are not detected as moved
+const minMovedLineLength = 3
+
+//single line of a hunk, the line number is 0 for the side the line does not exist on
+type HunkLine struct {
+	Origin  byte
+	OldLine int
+	NewLine int
+	Content string
+}
+
+//continuous block of changed lines of a file, including the surrounding context lines
+type Hunk struct {
+	Path     string
+	OldPath  string
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []HunkLine
+
+	//line numbers of the next added line
+	nextOld int
+	nextNew int
+}
+
+func NewHunk(path string, oldPath string, oldStart int, oldLines int, newStart int, newLines int) *Hunk {
+
+	hunk := &Hunk{
+		Path:     path,
+		OldPath:  oldPath,
+		OldStart: oldStart,
+		OldLines: oldLines,
+		NewStart: newStart,
+		NewLines: newLines,
+		Lines:    []HunkLine{},
+		nextOld:  oldStart,
+		nextNew:  newStart,
+	}
+
+	//the start of an empty side is the line before the hunk
+	if oldLines == 0 {
+		hunk.nextOld++
+	}
+	if newLines == 0 {
+		hunk.nextNew++
+	}
+	return hunk
+}
+
+//appends the next line of the hunk, the line numbers are derived from the previous lines
+func (h *Hunk) AddLine(origin byte, content string) {
+	line := HunkLine{Origin: origin, Content: strings.TrimRight(content, "\r\n")}
+
+	switch origin {
+	case LineAdded:
+		line.NewLine = h.nextNew
+		h.nextNew++
+	case LineRemoved:
+		line.OldLine = h.nextOld
+		h.nextOld++
+	default:
+		line.Origin = LineContext
+		line.OldLine = h.nextOld
+		line.NewLine = h.nextNew
+		h.nextOld++
+		h.nextNew++
+	}
+
+	h.Lines = append(h.Lines, line)
+}
+
+//added lines of the hunk
+func (h *Hunk) Added() []HunkLine {
+	return h.filter(LineAdded)
+}
+
+//removed lines of the hunk
+func (h *Hunk) Removed() []HunkLine {
+	return h.filter(LineRemoved)
+}
+
+func (h *Hunk) filter(origin byte) []HunkLine {
+	lines := []HunkLine{}
+	for _, line := range h.Lines {
+		if line.Origin == origin {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+//number of added and removed lines
+func (h *Hunk) LineDiff() LineDiff {
+	return LineDiff{len(h.Added()), len(h.Removed())}
+}
+
+/*
+checks if the hunk changes any of the lines start to end (inclusive) of the new
+version of the file, removed lines touch the range if they were located
+between its lines
+*/
+func (h *Hunk) Touches(start int, end int) bool {
+
+	//position of removed lines within the new version
+	position := h.NewStart
+	if h.NewLines == 0 {
+		position++
+	}
+
+	for _, line := range h.Lines {
+		switch line.Origin {
+		case LineAdded:
+			if line.NewLine >= start && line.NewLine <= end {
+				return true
+			}
+			position = line.NewLine + 1
+		case LineRemoved:
+			if position > start && position <= end {
+				return true
+			}
+		default:
+			position = line.NewLine + 1
+		}
+	}
+	return false
+}
+
+//checks if the hunk changes only whitespace (indentation, line breaks, trailing spaces)
+func (h *Hunk) IsWhitespaceOnly() bool {
+
+	added, removed := h.Added(), h.Removed()
+	if len(added) == 0 && len(removed) == 0 {
+		return false
+	}
+	return stripWhitespace(added) == stripWhitespace(removed)
+}
+
+//concatenates the contents of the lines without any whitespace
+func stripWhitespace(lines []HunkLine) string {
+	result := []rune{}
+	for _, line := range lines {
+		for _, r := range line.Content {
+			if unicode.IsSpace(r) == false {
+				result = append(result, r)
+			}
+		}
+	}
+	return string(result)
+}
+
+//line which was removed at one place and added at another one within the same diff
+type MovedLine struct {
+	Content string
+	OldPath string
+	OldLine int
+	Path    string
+	NewLine int
+}
+
+//adds a hunk of a file
+func (diff *ParentDiff) AddHunk(hunk *Hunk) {
+	diff.Hunks[hunk.Path] = append(diff.Hunks[hunk.Path], hunk)
+}
+
+//checks if all changes of a file are whitespace only (false if no hunks are known)
+func (diff *ParentDiff) IsWhitespaceOnly(path string) bool {
+	hunks := diff.Hunks[path]
+	for _, hunk := range hunks {
+		if hunk.IsWhitespaceOnly() == false {
+			return false
+		}
+	}
+	return len(hunks) > 0
+}
+
+/*
+finds lines which were moved within a file or between files: removed and added
+lines with the same content (ignoring surrounding whitespace), which are not
+replaced in place (e.g. a changed indentation), every removed line is matched
+to at most one added line
+*/
+func (diff *ParentDiff) MovedLines() []MovedLine {
+
+	//removed line and its block of continuous changes
+	type removedLine struct {
+		hunk  *Hunk
+		block int
+		line  HunkLine
+	}
+
+	paths := []string{}
+	for path := range diff.Hunks {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	removed := map[string][]removedLine{}
+	for _, path := range paths {
+		for _, hunk := range diff.Hunks[path] {
+			blocks := hunk.changeBlocks()
+			for n, line := range hunk.Lines {
+				content := strings.TrimSpace(line.Content)
+				if line.Origin == LineRemoved && len(content) >= minMovedLineLength {
+					removed[content] = append(removed[content], removedLine{hunk, blocks[n], line})
+				}
+			}
+		}
+	}
+
+	moved := []MovedLine{}
+	for _, path := range paths {
+		for _, hunk := range diff.Hunks[path] {
+			blocks := hunk.changeBlocks()
+			for n, line := range hunk.Lines {
+				if line.Origin != LineAdded {
+					continue
+				}
+				content := strings.TrimSpace(line.Content)
+				candidates := removed[content]
+				for i, candidate := range candidates {
+					if candidate.hunk == hunk && candidate.block == blocks[n] {
+						continue
+					}
+					moved = append(moved, MovedLine{
+						Content: content,
+						OldPath: candidate.hunk.OldPath,
+						OldLine: candidate.line.OldLine,
+						Path:    path,
+						NewLine: line.NewLine,
+					})
+					removed[content] = append(candidates[:i:i], candidates[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	return moved
+}
+
+//numbers the blocks of continuous changed lines, separated by context lines
+func (h *Hunk) changeBlocks() []int {
+	blocks := make([]int, len(h.Lines))
+	block := 0
+	for n, line := range h.Lines {
+		if line.Origin == LineContext {
+			block++
+		}
+		blocks[n] = block
+	}
+	return blocks
+}
+
+/*
+groups the lines of a complete file diff into hunks with the usual number of
+context lines, used for diffs which are not split into hunks (e.g. by go-git)
+*/
+func splitHunks(path string, oldPath string, lines []HunkLine) []*Hunk {
+
+	changed := []int{}
+	for n, line := range lines {
+		if line.Origin != LineContext {
+			changed = append(changed, n)
+		}
+	}
+
+	//line numbers before each line
+	oldLine, newLine := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for n, line := range lines {
+		oldLine[n+1], newLine[n+1] = oldLine[n], newLine[n]
+		if line.Origin != LineAdded {
+			oldLine[n+1]++
+		}
+		if line.Origin != LineRemoved {
+			newLine[n+1]++
+		}
+	}
+
+	hunks := []*Hunk{}
+	for i := 0; i < len(changed); {
+		//changes with less than two contexts in between belong to the same hunk
+		j := i
+		for j+1 < len(changed) && changed[j+1]-changed[j] <= 2*hunkContext+1 {
+			j++
+		}
+
+		first, last := changed[i]-hunkContext, changed[j]+hunkContext+1
+		if first < 0 {
+			first = 0
+		}
+		if last > len(lines) {
+			last = len(lines)
+		}
+
+		oldLines, newLines := oldLine[last]-oldLine[first], newLine[last]-newLine[first]
+		oldStart, newStart := oldLine[first]+1, newLine[first]+1
+		if oldLines == 0 {
+			oldStart--
+		}
+		if newLines == 0 {
+			newStart--
+		}
+
+		hunk := NewHunk(path, oldPath, oldStart, oldLines, newStart, newLines)
+		for _, line := range lines[first:last] {
+			hunk.AddLine(line.Origin, line.Content)
+		}
+		hunks = append(hunks, hunk)
+		i = j + 1
+	}
+	return hunks
+}
@@ -0,0 +1,148 @@
+package vcs
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseHunkRange(t *testing.T) {
+
+	tests := []struct {
+		header             string
+		oldStart, oldLines int
+		newStart, newLines int
+	}{
+		{"@@ -1,5 +1,6 @@", 1, 5, 1, 6},
+		{"@@ -10,2 +12,3 @@ function f() {", 10, 2, 12, 3},
+		//omitted counts are 1
+		{"@@ -1 +0,0 @@", 1, 1, 0, 0},
+		{"@@ -0,0 +1 @@", 0, 0, 1, 1},
+		{"@@ -3 +3 @@", 3, 1, 3, 1},
+		{"@@ -a,1 +1,1 @@", -1, 0, 1, 1},
+		{"@@ @@", -1, 0, -1, 0},
+	}
+
+	for _, test := range tests {
+		oldStart, oldLines := parseHunkRange(test.header, "-")
+		newStart, newLines := parseHunkRange(test.header, "+")
+		if oldStart != test.oldStart || oldLines != test.oldLines || newStart != test.newStart || newLines != test.newLines {
+			t.Errorf("expected -%d,%d +%d,%d for %q, got -%d,%d +%d,%d", test.oldStart, test.oldLines, test.newStart,
+				test.newLines, test.header, oldStart, oldLines, newStart, newLines)
+		}
+	}
+}
+
+func TestSplitHunks(t *testing.T) {
+
+	tests := []struct {
+		//origin of every line
+		origins string
+		hunks   []string
+	}{
+		//more than two contexts between the changes
+		{"  -+          +  ", []string{"-1,6 +1,6", "-11,5 +11,6"}},
+		{"+       +", []string{"-1,3 +1,4", "-5,3 +6,4"}},
+		//two contexts at most
+		{"+      +", []string{"-1,6 +1,8"}},
+		{"---", []string{"-1,3 +0,0"}},
+		{"+", []string{"-0,0 +1,1"}},
+		{"   ", []string{}},
+	}
+
+	for _, test := range tests {
+		lines := []HunkLine{}
+		for n, origin := range []byte(test.origins) {
+			lines = append(lines, HunkLine{Origin: origin, Content: fmt.Sprintf("line %d", n)})
+		}
+
+		hunks := []string{}
+		for _, hunk := range splitHunks("a.txt", "a.txt", lines) {
+			hunks = append(hunks, fmt.Sprintf("-%d,%d +%d,%d", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines))
+
+			//the line numbers continue from the header
+			oldLine, newLine := hunk.OldStart, hunk.NewStart
+			for _, line := range hunk.Lines {
+				if line.Origin != LineAdded {
+					if line.OldLine != oldLine {
+						t.Errorf("expected old line %d for %q of %q, got %d", oldLine, line.Content, test.origins, line.OldLine)
+					}
+					oldLine++
+				}
+				if line.Origin != LineRemoved {
+					if line.NewLine != newLine {
+						t.Errorf("expected new line %d for %q of %q, got %d", newLine, line.Content, test.origins, line.NewLine)
+					}
+					newLine++
+				}
+			}
+		}
+		if reflect.DeepEqual(hunks, test.hunks) == false {
+			t.Errorf("expected the hunks %v for %q, got %v", test.hunks, test.origins, hunks)
+		}
+	}
+}
+
+//creates a hunk from lines prefixed by their origin
+func testHunk(path string, oldStart int, newStart int, lines ...string) *Hunk {
+	hunk := NewHunk(path, path, oldStart, 1, newStart, 1)
+	for _, line := range lines {
+		hunk.AddLine(line[0], line[1:])
+	}
+	return hunk
+}
+
+func TestMovedLines(t *testing.T) {
+
+	diff := NewParentDiff("")
+	diff.AddHunk(testHunk("a.php", 1, 1,
+		" <?php",
+		"-$moved = 1;",
+		"-$dup = 1;",
+		"-$dup = 1;",
+		" $x = 1;",
+		//changed in place
+		"-  $indent = 1;",
+		"+$indent = 1;",
+		//moved within the hunk
+		"-$y = 2;",
+		" ",
+		"+$y = 2;",
+		"-}"))
+	diff.AddHunk(testHunk("b.php", 4, 4,
+		" $z = 1;",
+		"+    $moved = 1;",
+		"+$dup = 1;",
+		"+}"))
+
+	expected := []MovedLine{
+		{"$y = 2;", "a.php", 7, "a.php", 5},
+		{"$moved = 1;", "a.php", 2, "b.php", 5},
+		{"$dup = 1;", "a.php", 3, "b.php", 6},
+	}
+	if moved := diff.MovedLines(); reflect.DeepEqual(moved, expected) == false {
+		t.Errorf("expected %v, got %v", expected, moved)
+	}
+}
+
+func TestIsWhitespaceOnly(t *testing.T) {
+
+	tests := []struct {
+		lines      []string
+		whitespace bool
+	}{
+		{[]string{"-  foo();", "+\tfoo();"}, true},
+		{[]string{"-foo();  ", "+foo();"}, true},
+		{[]string{"-foo(a, b);", "+foo(a,", "+    b);"}, true},
+		{[]string{" foo();", "+"}, true},
+		{[]string{"-foo();", "+bar();"}, false},
+		{[]string{"-foo();"}, false},
+		{[]string{" foo();"}, false},
+	}
+
+	for _, test := range tests {
+		if whitespace := testHunk("a.php", 1, 1, test.lines...).IsWhitespaceOnly(); whitespace != test.whitespace {
+			t.Errorf("expected %v for %q, got %v", test.whitespace, test.lines, whitespace)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package vcs
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+)
+
+//connectors which are able to read files (e.g. .mailmap) of the analyzed revision
+type fileReader interface {
+	ReadFile(path string) ([]byte, error)
+}
+
+//single rule of a .mailmap file, empty values are not set within the rule
+type mailmapEntry struct {
+	ProperName  string
+	ProperEmail string
+	CommitName  string
+	CommitEmail string
+}
+
+type mailmap []mailmapEntry
+
+/*
+parses the content of a .mailmap file, supported forms are:
+	Proper Name <commit@email>
+	<proper@email> <commit@email>
+	Proper Name <proper@email> <commit@email>
+	Proper Name <proper@email> Commit Name <commit@email>
+*/
+func parseMailmap(content []byte) mailmap {
+
+	entries := mailmap{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		names := []string{}
+		emails := []string{}
+		for {
+			start := strings.Index(line, "<")
+			end := strings.Index(line, ">")
+			if start < 0 || end < start {
+				break
+			}
+			names = append(names, strings.TrimSpace(line[:start]))
+			emails = append(emails, strings.TrimSpace(line[start+1:end]))
+			line = line[end+1:]
+		}
+
+		switch len(emails) {
+		case 1:
+			entries = append(entries, mailmapEntry{ProperName: names[0], CommitEmail: emails[0]})
+		case 2:
+			entries = append(entries, mailmapEntry{
+				ProperName:  names[0],
+				ProperEmail: emails[0],
+				CommitName:  names[1],
+				CommitEmail: emails[1],
+			})
+		}
+	}
+	return entries
+}
+
+//returns the proper name and email for an identity, later rules have precedence
+func (m mailmap) lookup(name string, email string) (string, string) {
+	for i := len(m) - 1; i >= 0; i-- {
+		entry := m[i]
+		if strings.EqualFold(entry.CommitEmail, email) == false ||
+			(entry.CommitName != "" && strings.EqualFold(entry.CommitName, name) == false) {
+			continue
+		}
+
+		if entry.ProperName != "" {
+			name = entry.ProperName
+		}
+		if entry.ProperEmail != "" {
+			email = entry.ProperEmail
+		}
+		break
+	}
+	return name, email
+}
+
+//lower case email without sub-addressing (e.g. "Jo+work@Example.org" -> "jo@example.org")
+func normalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		if plus := strings.Index(email[:at], "+"); plus >= 0 {
+			email = email[:plus] + email[at:]
+		}
+	}
+	return email
+}
+
+/*
+merges developers with multiple identities, identities are mapped by the
+.mailmap of the repository and the alias file, optionally developers with
+the same name or normalized email are merged too
+*/
+func (r *Repository) mergeIdentities(connector Connector) error {
+
+	rules := mailmap{}
+	if reader, ok := connector.(fileReader); ok && UseMailmap {
+		if content, err := reader.ReadFile(".mailmap"); err == nil {
+			rules = append(rules, parseMailmap(content)...)
+		}
+	}
+	if AliasFile != "" {
+		content, err := ioutil.ReadFile(AliasFile)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, parseMailmap(content)...)
+	}
+
+	type identity struct {
+		dev   *Developer
+		name  string
+		email string
+		rank  int
+	}
+
+	//sort for a deterministic choice of the merged identity
+	identities := []*identity{}
+	for _, dev := range r.Developers {
+		name, email := rules.lookup(dev.Name, dev.Email)
+		if NormalizeEmails {
+			email = normalizeEmail(email)
+		}
+		identities = append(identities, &identity{dev: dev, name: name, email: email})
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		if len(identities[i].dev.Commits) != len(identities[j].dev.Commits) {
+			return len(identities[i].dev.Commits) > len(identities[j].dev.Commits)
+		}
+		return identities[i].dev.Id < identities[j].dev.Id
+	})
+	for rank, crt := range identities {
+		crt.rank = rank
+	}
+
+	//group identities by email (and name)
+	groups := map[*identity]*identity{}
+	var find func(crt *identity) *identity
+	find = func(crt *identity) *identity {
+		if groups[crt] != crt {
+			groups[crt] = find(groups[crt])
+		}
+		return groups[crt]
+	}
+
+	known := map[string]*identity{}
+	for _, crt := range identities {
+		groups[crt] = crt
+
+		keys := []string{"email:" + strings.ToLower(crt.email)}
+		if crt.email == "" {
+			keys = []string{"id:" + crt.dev.Id}
+		}
+		if MergeByName && strings.TrimSpace(crt.name) != "" {
+			keys = append(keys, "name:"+strings.ToLower(strings.TrimSpace(crt.name)))
+		}
+
+		for _, key := range keys {
+			if other, exists := known[key]; exists {
+				//the identity with most commits represents the group
+				first, second := find(other), find(crt)
+				if second.rank < first.rank {
+					first, second = second, first
+				}
+				groups[second] = first
+			} else {
+				known[key] = crt
+			}
+		}
+	}
+
+	developers := map[string]*Developer{}
+	merged := map[*identity]*Developer{}
+	replaced := map[*Developer]*Developer{}
+	for _, crt := range identities {
+		first := find(crt)
+		dev, exists := merged[first]
+		if !exists {
+			id := first.email
+			if id == "" {
+				id = first.dev.Id
+			}
+			dev = NewDeveloper(id, first.email, first.name)
+			merged[first] = dev
+			developers[id] = dev
+		}
+
+		for id, commit := range crt.dev.Commits {
+			commit.Developer = dev
+			dev.Commits[id] = commit
+		}
+		replaced[crt.dev] = dev
+	}
+
+	mergedDev := func(dev *Developer) *Developer {
+		if merged, exists := replaced[dev]; exists {
+			return merged
+		}
+		return dev
+	}
+
+	//committers and co-authors are merged too, co-authors could become the author
+	for _, commit := range r.Commits {
+		if committer := commit.Committer; committer != nil {
+			commit.Committer = nil
+			commit.SetCommitter(mergedDev(committer))
+		}
+		coAuthors := commit.CoAuthors
+		commit.CoAuthors = nil
+		for _, coAuthor := range coAuthors {
+			commit.AddCoAuthor(mergedDev(coAuthor))
+		}
+	}
+
+	if len(developers) < len(r.Developers) {
+		log.Printf("merged %d identities into %d developers", len(r.Developers), len(developers))
+	}
+	r.Developers = developers
+
+	return nil
+}
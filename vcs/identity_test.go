@@ -0,0 +1,134 @@
+package vcs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseMailmap(t *testing.T) {
+
+	tests := []struct {
+		line  string
+		entry mailmapEntry
+	}{
+		{"Jo Doe <jo@example.com>", mailmapEntry{ProperName: "Jo Doe", CommitEmail: "jo@example.com"}},
+		{"<jo@example.com> <jo@old.example.com>", mailmapEntry{ProperEmail: "jo@example.com", CommitEmail: "jo@old.example.com"}},
+		{"Jo Doe <jo@example.com> <jo@old.example.com>",
+			mailmapEntry{ProperName: "Jo Doe", ProperEmail: "jo@example.com", CommitEmail: "jo@old.example.com"}},
+		{"Jo Doe <jo@example.com> jo <jo@old.example.com>",
+			mailmapEntry{ProperName: "Jo Doe", ProperEmail: "jo@example.com", CommitName: "jo", CommitEmail: "jo@old.example.com"}},
+		{"  Jo Doe   <jo@example.com>  # comment <x@example.com>", mailmapEntry{ProperName: "Jo Doe", CommitEmail: "jo@example.com"}},
+	}
+
+	for _, test := range tests {
+		if entries := parseMailmap([]byte(test.line)); len(entries) != 1 || entries[0] != test.entry {
+			t.Errorf("expected %v for %q, got %v", test.entry, test.line, entries)
+		}
+	}
+
+	if entries := parseMailmap([]byte("# comment\n\nJo Doe\n")); len(entries) != 0 {
+		t.Errorf("expected no rules, got %v", entries)
+	}
+}
+
+func TestMailmapLookup(t *testing.T) {
+
+	rules := parseMailmap([]byte("Jo Doe <jo@example.com>\n" +
+		"<jo@example.com> <jo@old.example.com>\n" +
+		"Jo Doe <jo@example.com> build <ci@example.com>\n"))
+
+	tests := []struct {
+		name, email        string
+		properName, proper string
+	}{
+		{"jo", "JO@example.com", "Jo Doe", "JO@example.com"},
+		{"jo", "jo@old.example.com", "jo", "jo@example.com"},
+		{"Build", "ci@example.com", "Jo Doe", "jo@example.com"},
+		{"bot", "ci@example.com", "bot", "ci@example.com"},
+		{"eve", "eve@example.com", "eve", "eve@example.com"},
+	}
+
+	for _, test := range tests {
+		if name, email := rules.lookup(test.name, test.email); name != test.properName || email != test.proper {
+			t.Errorf("expected %s <%s> for %s <%s>, got %s <%s>", test.properName, test.proper, test.name, test.email, name, email)
+		}
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+
+	tests := map[string]string{
+		"Jo+work@Example.org": "jo@example.org",
+		" jo@example.org ":    "jo@example.org",
+		"jo+a+b@example.org":  "jo@example.org",
+		"jo@sub+domain.org":   "jo@sub+domain.org",
+		"no-email":            "no-email",
+		"+jo@example.org":     "@example.org",
+	}
+
+	for email, expected := range tests {
+		if normalized := normalizeEmail(email); normalized != expected {
+			t.Errorf("expected %q for %q, got %q", expected, email, normalized)
+		}
+	}
+}
+
+/*
+identities are merged transitively: the first two share their name, the second
+and third their normalized email and the fourth is mapped to the first by the
+alias file, the identity with most commits represents the developer
+*/
+func TestMergeIdentities(t *testing.T) {
+
+	AliasFile = filepath.Join(t.TempDir(), "aliases")
+	MergeByName, NormalizeEmails = true, true
+	defer func() { AliasFile, MergeByName, NormalizeEmails = "", false, false }()
+	if err := os.WriteFile(AliasFile, []byte("<jo@a.org> <old@c.org>\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBuilder()
+	authors := []string{"Jo Doe <jo@a.org>", "Jo Doe <jo@a.org>", "Jo Doe <jo@b.org>", "Johnny <JO+dev@b.org>", "Old <old@c.org>", "Eve <eve@e.org>"}
+	var first, head *Commit
+	for n, author := range authors {
+		spec := CommitSpec{Author: author, Committer: "Eve <eve@e.org>", Date: testDate(n), Message: author,
+			Write: map[string]string{"a.txt": author}}
+		if head != nil {
+			spec.Parents = []*Commit{head}
+		}
+		if n == 0 {
+			spec.Committer = "Johnny <JO+dev@b.org>"
+		}
+		head = b.Commit(spec)
+		if n == 0 {
+			first = head
+		}
+	}
+
+	repo := b.Repository()
+	if err := repo.mergeIdentities(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{}
+	for id := range repo.Developers {
+		ids = append(ids, id)
+	}
+	if len(ids) != 2 || repo.Developers["jo@a.org"] == nil || repo.Developers["eve@e.org"] == nil {
+		t.Fatalf("expected the developers jo@a.org and eve@e.org, got %v", ids)
+	}
+
+	jo := repo.Developers["jo@a.org"]
+	if jo.Name != "Jo Doe" || len(jo.Commits) != 5 {
+		t.Errorf("expected Jo Doe with 5 commits, got %s with %d", jo.Name, len(jo.Commits))
+	}
+	for _, commit := range repo.Commits {
+		if commit.Developer != repo.Developers[commit.Developer.Id] {
+			t.Errorf("commit %q refers to a replaced developer", commit.Message)
+		}
+	}
+	if first.Committer != jo || len(jo.Committed) != 1 || jo.Committed[first.Id] != first {
+		t.Errorf("expected jo as committer of the first commit, got %v", first.Committer)
+	}
+}
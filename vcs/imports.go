@@ -0,0 +1,251 @@
+package vcs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//submodule of the analyzed revision (a gitlink within the tree of a git repository)
+type Submodule struct {
+	Path string
+	Url  string
+	//commit of the submodule recorded by the analyzed revision
+	Commit string
+	//submodule loaded as separate repository, only set for ImportRecurse
+	Repository *Repository
+}
+
+//connectors which are able to detect submodules within the analyzed revision
+type submoduleConnector interface {
+	fileReader
+	//returns the commits of all gitlinks by path
+	gitlinks() (map[string]string, error)
+}
+
+//directory of a tree, the hashes of its entries by name
+type treeDir struct {
+	hash    string
+	entries map[string]string
+}
+
+/*
+returns the prefix of a parent tree merged as subtree (e.g. by git subtree)
+into the directories of a tree, empty if it is a regular merge: the parent
+tree is either equal to a subdirectory or has the same entries (changed by
+local modifications), but different entries than the root directory
+*/
+func subtreePrefix(dirs map[string]treeDir, parent treeDir) string {
+
+	if len(parent.entries) == 0 {
+		return ""
+	}
+
+	paths := []string{}
+	for path := range dirs {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if dirs[path].hash == parent.hash {
+			return path
+		}
+	}
+
+	if sameEntries(dirs[""], parent) {
+		return ""
+	}
+	for _, path := range paths {
+		if sameEntries(dirs[path], parent) {
+			return path
+		}
+	}
+	return ""
+}
+
+//checks if two directories contain entries with the same names
+func sameEntries(dir treeDir, other treeDir) bool {
+	if len(dir.entries) != len(other.entries) {
+		return false
+	}
+	for name := range other.entries {
+		if _, exists := dir.entries[name]; exists == false {
+			return false
+		}
+	}
+	return true
+}
+
+//checks if a parent of the commit is not followed, because it was merged as subtree and imports are skipped
+func (c *Commit) skipsSubtree(parentId string) bool {
+	_, subtree := c.Subtrees[parentId]
+	return subtree && ImportPolicy != ImportInclude
+}
+
+/*
+converts the changes of files imported by a subtree merge: they are existing
+files, which are neither added nor changed and their lines are not counted,
+removed and moved files are kept (without lines) to keep the tree complete
+*/
+func (diff *ParentDiff) markImported(prefix string) {
+
+	imported := func(path string) bool {
+		return strings.HasPrefix(path, prefix+"/")
+	}
+
+	for path, file := range diff.AddedFiles {
+		if imported(path) {
+			diff.ExistingFiles[path] = file
+			delete(diff.AddedFiles, path)
+		}
+	}
+	for path, file := range diff.ChangedFiles {
+		if imported(path) {
+			diff.ExistingFiles[path] = file
+			delete(diff.ChangedFiles, path)
+		}
+	}
+	for path, lines := range diff.FileLines {
+		if imported(path) {
+			diff.LineDiff.Added -= lines.Added
+			diff.LineDiff.Removed -= lines.Removed
+			delete(diff.FileLines, path)
+			delete(diff.Hunks, path)
+		}
+	}
+}
+
+/*
+detects the submodules of the analyzed revision by their gitlinks and the urls
+within .gitmodules, with ImportRecurse every submodule is loaded as separate
+repository at its recorded commit (submodules which cannot be loaded are only
+listed)
+*/
+func (r *Repository) loadSubmodules(connector Connector) error {
+
+	r.Submodules = []*Submodule{}
+	detector, ok := connector.(submoduleConnector)
+	if ok == false {
+		return nil
+	}
+
+	gitlinks, err := detector.gitlinks()
+	if err != nil || len(gitlinks) == 0 {
+		return err
+	}
+
+	urls := map[string]string{}
+	if content, err := detector.ReadFile(".gitmodules"); err == nil {
+		urls = parseGitmodules(content)
+	}
+
+	paths := []string{}
+	for path := range gitlinks {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		submodule := &Submodule{Path: path, Url: urls[path], Commit: gitlinks[path]}
+		r.Submodules = append(r.Submodules, submodule)
+
+		if ImportPolicy != ImportRecurse {
+			continue
+		}
+		if submodule.Repository, err = r.loadSubmodule(submodule); err != nil {
+			log.Printf("unable to load submodule %s: %s", path, err)
+		}
+	}
+
+	log.Printf("detected %d submodules", len(r.Submodules))
+	return nil
+}
+
+/*
+loads a submodule at its recorded commit, an initialized submodule within a
+local working copy is preferred to its url (relative urls are resolved against
+the path of the repository), the snapshot and the path rules (include and
+exclude globs) are only used for the repository itself
+*/
+func (r *Repository) loadSubmodule(submodule *Submodule) (*Repository, error) {
+
+	source := submodule.Url
+	checkout := filepath.Join(r.path, filepath.FromSlash(submodule.Path))
+	if _, err := os.Stat(filepath.Join(checkout, ".git")); err == nil {
+		source = checkout
+	} else if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+		source = resolveRelativeUrl(r.path, source)
+	}
+	if source == "" {
+		return nil, errors.New("url is missing within .gitmodules")
+	}
+
+	revision, snapshotFile, blobDir, paths := Revision, SnapshotFile, Blobs.Dir, Paths
+	Revision, SnapshotFile, Paths = submodule.Commit, "", &PathFilter{}
+	defer func() {
+		Revision, SnapshotFile, Blobs.Dir, Paths = revision, snapshotFile, blobDir, paths
+	}()
+
+	log.Printf("loading submodule %s from %s", submodule.Path, source)
+	return NewRepository(source)
+}
+
+//resolves a relative submodule url against the (remote) path of the repository
+func resolveRelativeUrl(base string, relative string) string {
+	if remote, err := url.Parse(base); err == nil && remote.Scheme != "" {
+		remote.Path = path.Join(remote.Path, relative)
+		return remote.String()
+	}
+	if scpPattern.MatchString(base) {
+		host := scpPattern.FindString(base)
+		return host + path.Join(strings.TrimPrefix(base, host), relative)
+	}
+	return filepath.Join(base, filepath.FromSlash(relative))
+}
+
+//reads the urls of the submodules by path from a .gitmodules file
+func parseGitmodules(content []byte) map[string]string {
+
+	urls := map[string]string{}
+	var modulePath, moduleUrl string
+	add := func() {
+		if modulePath != "" {
+			urls[modulePath] = moduleUrl
+		}
+		modulePath, moduleUrl = "", ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			add()
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(line[i+1:]), `"`)
+		switch strings.TrimSpace(line[:i]) {
+		case "path":
+			modulePath = strings.TrimSuffix(value, "/")
+		case "url":
+			moduleUrl = value
+		}
+	}
+	add()
+
+	return urls
+}
@@ -0,0 +1,205 @@
+package vcs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path"
+	"strings"
+)
+
+//rule of the .gitattributes, the last matching rule of an attribute wins
+type attributeRule struct {
+	pattern  string
+	excluded bool
+}
+
+/*
+include and exclude rules for file paths, patterns are globs like in .gitignore:
+patterns without a slash match in every directory, "**" matches any number of
+directories and a matching directory includes all files below it
+*/
+type PathFilter struct {
+	Include    []string
+	Exclude    []string
+	attributes map[string][]attributeRule
+}
+
+//checks if a path passes the include and exclude rules
+func (filter *PathFilter) Valid(path string) bool {
+
+	if len(filter.Include) > 0 {
+		included := false
+		for _, pattern := range filter.Include {
+			if matchPathPattern(pattern, path) {
+				included = true
+				break
+			}
+		}
+		if included == false {
+			return false
+		}
+	}
+
+	for _, pattern := range filter.Exclude {
+		if matchPathPattern(pattern, path) {
+			return false
+		}
+	}
+
+	//every attribute is resolved on its own, a path is excluded if one of them is set
+	for _, rules := range filter.attributes {
+		for i := len(rules) - 1; i >= 0; i-- {
+			if matchPathPattern(rules[i].pattern, path) {
+				if rules[i].excluded {
+					return false
+				}
+				break
+			}
+		}
+	}
+
+	return true
+}
+
+/*
+reads rules from a config file, every line contains a single rule:
+	include src/**
+	exclude vendor/
+	# comment
+*/
+func (filter *PathFilter) LoadConfig(filename string) error {
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: invalid rule, e.g.: exclude vendor/", filename, n)
+		}
+
+		switch fields[0] {
+		case "include":
+			filter.Include = append(filter.Include, fields[1])
+		case "exclude":
+			filter.Exclude = append(filter.Exclude, fields[1])
+		default:
+			return fmt.Errorf("%s:%d: unknown rule type %q", filename, n, fields[0])
+		}
+	}
+	return scanner.Err()
+}
+
+//excludes vendored and generated files (linguist-vendored, linguist-generated) of a .gitattributes file
+func (filter *PathFilter) AddAttributes(content []byte) {
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		for _, attribute := range fields[1:] {
+			name, value := attribute, "true"
+			if i := strings.Index(attribute, "="); i >= 0 {
+				name, value = attribute[:i], attribute[i+1:]
+			}
+			if strings.HasPrefix(name, "-") || strings.HasPrefix(name, "!") {
+				name, value = name[1:], "false"
+			}
+
+			if name == "linguist-vendored" || name == "linguist-generated" {
+				if filter.attributes == nil {
+					filter.attributes = map[string][]attributeRule{}
+				}
+				filter.attributes[name] = append(filter.attributes[name], attributeRule{fields[0], value != "false"})
+			}
+		}
+	}
+}
+
+//path rules of the .gitattributes of a repository, embedded by the connectors
+type pathAttributes struct {
+	attributes PathFilter
+}
+
+//reads the .gitattributes of the analyzed revision, the rules only apply to this repository
+func (a *pathAttributes) loadPathAttributes(reader fileReader) {
+	a.attributes = PathFilter{}
+	if content, err := reader.ReadFile(".gitattributes"); err == nil {
+		a.attributes.AddAttributes(content)
+		log.Printf("loaded path rules from .gitattributes")
+	}
+}
+
+//checks the language filter, the path rules and the .gitattributes of the repository for a file
+func (a *pathAttributes) validPath(path string) bool {
+	return ValidPath(path) && a.attributes.Valid(path)
+}
+
+//checks the language filter and path rules for a file
+func ValidPath(path string) bool {
+	return Filter.ValidExtension(path) && Paths.Valid(path)
+}
+
+//checks if a pattern matches the path or one of its parent directories
+func matchPathPattern(pattern string, filepath string) bool {
+
+	isDir := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if anchored == false {
+		pattern = "**/" + pattern
+	}
+
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(strings.TrimPrefix(filepath, "/"), "/")
+
+	for n := len(pathParts); n > 0; n-- {
+		//directory patterns do not match the file itself
+		if isDir && n == len(pathParts) {
+			continue
+		}
+		if matchParts(patternParts, pathParts[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+//matches the parts of a glob pattern ("**" matches zero or more parts)
+func matchParts(pattern []string, parts []string) bool {
+
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchParts(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+
+	if matched, err := path.Match(pattern[0], parts[0]); err != nil || matched == false {
+		return false
+	}
+	return matchParts(pattern[1:], parts[1:])
+}
@@ -0,0 +1,40 @@
+package vcs
+
+import "testing"
+
+func TestPathAttributes(t *testing.T) {
+
+	tests := []struct {
+		attributes string
+		path       string
+		valid      bool
+	}{
+		{"vendor/** linguist-vendored", "vendor/lib/a.go", false},
+		{"vendor/** linguist-vendored", "src/a.go", true},
+		{"*.pb.go linguist-generated", "api/service.pb.go", false},
+		{"*.pb.go linguist-generated=true", "service.pb.go", false},
+		//negations
+		{"vendor/** linguist-vendored\nvendor/own/** -linguist-vendored", "vendor/own/a.go", true},
+		{"vendor/** linguist-vendored\nvendor/own/** !linguist-vendored", "vendor/own/a.go", true},
+		{"vendor/** linguist-vendored\nvendor/own/** linguist-vendored=false", "vendor/own/a.go", true},
+		{"vendor/** linguist-vendored\nvendor/own/** -linguist-vendored", "vendor/lib/a.go", false},
+		//the last matching rule wins
+		{"vendor/own/** -linguist-vendored\nvendor/** linguist-vendored", "vendor/own/a.go", false},
+		{"* linguist-generated\n*.go -linguist-generated", "a.go", true},
+		{"* linguist-generated\n*.go -linguist-generated", "a.php", false},
+		//the attributes are resolved separately
+		{"vendor/** linguist-vendored\nvendor/** -linguist-generated", "vendor/lib/a.go", false},
+		{"gen/** linguist-generated\ngen/** -linguist-vendored", "gen/a.go", false},
+		{"* -linguist-generated\n* linguist-vendored=false", "a.go", true},
+		//other attributes are ignored
+		{"*.go text eol=lf\n# vendor/** linguist-vendored", "vendor/a.go", true},
+	}
+
+	for _, test := range tests {
+		filter := PathFilter{}
+		filter.AddAttributes([]byte(test.attributes))
+		if valid := filter.Valid(test.path); valid != test.valid {
+			t.Errorf("expected %v for %s with %q, got %v", test.valid, test.path, test.attributes, valid)
+		}
+	}
+}
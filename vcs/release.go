@@ -0,0 +1,193 @@
+package vcs
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+//named commit of the repository, e.g. a release
+type Tag struct {
+	Name   string
+	Commit *Commit
+	//date of the tag (date of the commit for tags without own date)
+	Date time.Time
+}
+
+//connectors which are able to read the tags of the repository
+type tagLoader interface {
+	//returns the tags of all loaded commits
+	loadTags() ([]*Tag, error)
+}
+
+//orders tags by date, tags of the same date by name
+type tagsByDate []*Tag
+
+func (s tagsByDate) Len() int      { return len(s) }
+func (s tagsByDate) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s tagsByDate) Less(i, j int) bool {
+	if s[i].Date.Equal(s[j].Date) {
+		return s[i].Name < s[j].Name
+	}
+	return s[i].Date.Before(s[j].Date)
+}
+
+//commits of a release: all commits reachable from its tag, which are not part of a previous release
+type Release struct {
+	//nil for the commits after the latest release
+	Tag     *Tag
+	Commits map[string]*Commit
+}
+
+func (release *Release) Name() string {
+	if release.Tag == nil {
+		return "unreleased"
+	}
+	return release.Tag.Name
+}
+
+//returns the commits of the release in topological order
+func (release *Release) TopologicalOrder() []*Commit {
+	return topologicalOrder(release.Commits)
+}
+
+//loads the tags of the repository, if supported by the connector
+func (r *Repository) loadTags(connector Connector) error {
+
+	r.Tags = []*Tag{}
+	loader, ok := connector.(tagLoader)
+	if ok == false {
+		return nil
+	}
+
+	tags, err := loader.loadTags()
+	if err != nil {
+		return err
+	}
+	sort.Sort(tagsByDate(tags))
+	r.Tags = tags
+
+	log.Printf("loaded %d tags", len(r.Tags))
+	return nil
+}
+
+//removes tags of commits which are not part of the repository anymore
+func (r *Repository) removeUnknownTags() {
+	tags := []*Tag{}
+	for _, tag := range r.Tags {
+		if _, exists := r.Commits[tag.Commit.Id]; exists {
+			tags = append(tags, tag)
+		}
+	}
+	r.Tags = tags
+}
+
+/*
+splits the commits into releases by the tags (ordered by date), tags without
+new commits (e.g. several tags of the same commit) are skipped, commits after
+the latest tag are part of an additional release without tag
+*/
+func (r *Repository) Releases() []*Release {
+
+	released := map[string]bool{}
+	collect := func(head *Commit) map[string]*Commit {
+		commits := map[string]*Commit{}
+		stack := []*Commit{head}
+		for len(stack) > 0 {
+			commit := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if released[commit.Id] {
+				continue
+			}
+			released[commit.Id] = true
+			commits[commit.Id] = commit
+			for _, parent := range commit.Parents {
+				stack = append(stack, parent)
+			}
+		}
+		return commits
+	}
+
+	releases := []*Release{}
+	for _, tag := range r.Tags {
+		if commits := collect(tag.Commit); len(commits) > 0 {
+			releases = append(releases, &Release{Tag: tag, Commits: commits})
+		}
+	}
+
+	unreleased := map[string]*Commit{}
+	for _, head := range r.Heads() {
+		for id, commit := range collect(head) {
+			unreleased[id] = commit
+		}
+	}
+	if len(unreleased) > 0 {
+		releases = append(releases, &Release{Commits: unreleased})
+	}
+
+	return releases
+}
+
+/*
+returns a copy of the repository which contains only the given commits (e.g. of
+a release), commits and developers are copied, diffs and files are shared
+*/
+func (r *Repository) Subset(commits map[string]*Commit) *Repository {
+
+	subset := &Repository{
+		Commits:    map[string]*Commit{},
+		Developers: map[string]*Developer{},
+		Tags:       []*Tag{},
+		System:     r.System,
+		path:       r.path,
+		Workspace:  r.Workspace,
+	}
+
+	devs := map[*Developer]*Developer{}
+	copyDev := func(dev *Developer) *Developer {
+		if _, exists := devs[dev]; !exists {
+			devs[dev] = NewDeveloper(dev.Id, dev.Email, dev.Name)
+		}
+		return devs[dev]
+	}
+
+	for id, original := range commits {
+		commit := NewCommit(original.Id, original.Message, original.Date, copyDev(original.Developer))
+		commit.Files = original.Files
+		commit.Boundary = original.Boundary
+		commit.Subtrees = original.Subtrees
+		for _, diff := range original.Diffs {
+			commit.AddDiff(diff)
+		}
+		commit.Developer.Commits[id] = commit
+		if original.Committer != nil {
+			commit.SetCommitter(copyDev(original.Committer))
+		}
+		for _, coAuthor := range original.CoAuthors {
+			commit.AddCoAuthor(copyDev(coAuthor))
+		}
+		subset.Commits[id] = commit
+	}
+
+	for id, original := range commits {
+		for parentId := range original.Parents {
+			if parent, exists := subset.Commits[parentId]; exists {
+				subset.Commits[id].Parents[parentId] = parent
+				parent.Children[id] = subset.Commits[id]
+			}
+		}
+	}
+
+	for _, dev := range devs {
+		if dev.isEmpty() == false {
+			subset.Developers[dev.Id] = dev
+		}
+	}
+	for _, tag := range r.Tags {
+		if commit, exists := subset.Commits[tag.Commit.Id]; exists {
+			subset.Tags = append(subset.Tags, &Tag{tag.Name, commit, tag.Date})
+		}
+	}
+
+	return subset
+}
@@ -1,27 +1,55 @@
 package vcs
 
 import (
-	"crypto/sha1"
+	"errors"
 	"fmt"
-	"io"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 )
 
 const (
 	_ = iota
 	GIT
 	SVN
+	HG
+	GITLOG
+	//repository built in memory (see Builder)
+	MEMORY
 )
 
+var systemNames = map[int]string{
+	GIT:    "git",
+	SVN:    "svn",
+	HG:     "hg",
+	GITLOG: "git log",
+	MEMORY: "memory",
+}
+
+var ErrUnsupportedSystem = errors.New("unsupported version control system")
+
+//scp like syntax of git, e.g. "git@github.com:jochil/scabov"
+var scpPattern = regexp.MustCompile(`^[\w.-]+@[\w.-]+:`)
+
 //internal representation of an repository
 type Repository struct {
 	Commits    map[string]*Commit
 	Developers map[string]*Developer
+	Tags       []*Tag
+	System     int
+	//errors of corrupt objects (e.g. missing blobs), which were skipped while loading
+	Skipped []error
+	//submodules of the analyzed revision (only git)
+	Submodules []*Submodule
+	//reasons of files excluded by their content (e.g. binary or generated) by path
+	Excluded map[string]string
 
 	path      string
 	Workspace string
+	snapshot  *Snapshot
 }
 
 /*
@@ -29,20 +57,46 @@ initialize the repository (connector)
 */
 func NewRepository(path string) (*Repository, error) {
 
-	//TODO evaluate vcs type
-	system := GIT
+	system, err := detectSystem(path)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("detected %s repository", systemNames[system])
 
 	repo := &Repository{
-		path: path,
+		path:   path,
+		System: system,
 	}
 
-	repo.checkWorkspace()
+	if err := repo.checkWorkspace(); err != nil {
+		return nil, err
+	}
+
+	if DiskCache {
+		Blobs.Dir = filepath.Join(repo.Workspace, "blobs")
+	}
 
 	//get correct connector for given vcs
 	var connector Connector
 	switch system {
 	case GIT:
-		connector = &GitConnector{}
+		if connector, err = newGitConnector(GitBackend); err != nil {
+			return nil, err
+		}
+	case SVN:
+		connector = &SvnConnector{}
+	case HG:
+		connector = &HgConnector{}
+	case GITLOG:
+		connector = &GitLogConnector{}
+	}
+
+	//continue from the snapshot of a previous run
+	var restored *Snapshot
+	if resumable, ok := connector.(resumableConnector); ok && SnapshotFile != "" {
+		if restored = repo.loadSnapshot(); restored != nil {
+			resumable.resume(restored.restore(resumable))
+		}
 	}
 
 	//local or remote path?
@@ -54,19 +108,165 @@ func NewRepository(path string) (*Repository, error) {
 		return nil, err
 	}
 
+	if err := repo.writeWorkspaceInfo(); err != nil {
+		return nil, err
+	}
+
 	repo.Commits = connector.Commits()
 	repo.Developers = connector.Developers()
 
+	repo.Skipped = []error{}
+	if skipping, ok := connector.(skippingConnector); ok {
+		repo.Skipped = skipping.skippedErrors()
+	}
+	if len(repo.Skipped) > 0 {
+		log.Printf("skipped %d corrupt objects", len(repo.Skipped))
+	}
+
+	//files of restored commits were already classified by the previous run
+	repo.Excluded = map[string]string{}
+	changed := map[string]*Commit{}
+	for id, commit := range repo.Commits {
+		changed[id] = commit
+	}
+	if restored != nil {
+		for path, reason := range restored.Excluded {
+			repo.Excluded[path] = reason
+		}
+		for _, crt := range restored.Commits {
+			delete(changed, crt.Id)
+		}
+	}
+	if excluding, ok := connector.(excludingConnector); ok {
+		for path, reason := range excluding.excludedFiles() {
+			repo.Excluded[path] = reason
+		}
+	}
+	repo.classifyFiles(changed)
+	repo.removeExcludedFiles()
+
+	removed := 0
+	if restored != nil {
+		if removed, err = repo.removeUnreachable(connector); err != nil {
+			return nil, err
+		}
+		if removed > 0 {
+			log.Printf("history was rewritten, removed %d commits of the snapshot", removed)
+		}
+		log.Printf("loaded %d new commits", len(repo.Commits)-len(restored.Commits)+removed)
+	}
+
+	if err := repo.loadTags(connector); err != nil {
+		log.Printf("unable to load tags: %s", err)
+	}
+
+	if SnapshotFile != "" {
+		repo.snapshot = newSnapshot(path, system, repo.Commits, repo.Developers)
+		repo.snapshot.Excluded = repo.Excluded
+
+		//data of other packages is only valid for an unchanged history
+		if restored != nil && removed == 0 {
+			repo.snapshot.Data = restored.Data
+		}
+	}
+
+	if err := repo.mergeIdentities(connector); err != nil {
+		return nil, err
+	}
+
+	if err := repo.selectWindow(connector); err != nil {
+		return nil, err
+	}
+
+	if err := repo.loadSubmodules(connector); err != nil {
+		log.Printf("unable to detect submodules: %s", err)
+	}
+
 	return repo, nil
 }
 
-//TODO replace this naive approach
-func (r *Repository) FirstCommit() *Commit {
-	for _, commit := range r.Commits {
-		if len(commit.Parents) == 0 {
-			return commit
+/*
+removes all commits outside of the selected range and time window, the diffs of
+the remaining commits are still based on their (removed) parents
+*/
+func (r *Repository) selectWindow(connector Connector) error {
+
+	excluded := map[string]bool{}
+
+	if from, _ := splitRange(Revision); from != "" {
+		id, err := connector.Resolve(from)
+		if err != nil {
+			return err
+		}
+
+		//exclude the lower bound and all of its ancestors
+		stack := []*Commit{r.Commits[id]}
+		for len(stack) > 0 {
+			commit := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if commit == nil || excluded[commit.Id] {
+				continue
+			}
+			excluded[commit.Id] = true
+			for _, parent := range commit.Parents {
+				stack = append(stack, parent)
+			}
+		}
+	}
+
+	for id, commit := range r.Commits {
+		if excluded[id] ||
+			(Since.IsZero() == false && commit.Date.Before(Since)) ||
+			(Until.IsZero() == false && commit.Date.After(Until)) {
+			r.removeCommit(commit)
+		}
+	}
+
+	for id, dev := range r.Developers {
+		if dev.isEmpty() {
+			delete(r.Developers, id)
 		}
 	}
+
+	r.removeUnknownTags()
+
+	log.Printf("selected %d commits of %d developers", len(r.Commits), len(r.Developers))
+	return nil
+}
+
+//removes a commit from the repository and unlinks it from its parents and children
+func (r *Repository) removeCommit(commit *Commit) {
+	for _, parent := range commit.Parents {
+		delete(parent.Children, commit.Id)
+	}
+	for _, child := range commit.Children {
+		delete(child.Parents, commit.Id)
+	}
+	delete(commit.Developer.Commits, commit.Id)
+	if commit.Committer != nil {
+		delete(commit.Committer.Committed, commit.Id)
+	}
+	for _, coAuthor := range commit.CoAuthors {
+		delete(coAuthor.CoAuthored, commit.Id)
+	}
+	delete(r.Commits, commit.Id)
+}
+
+//name of the used version control system, e.g. "git"
+func (r *Repository) SystemName() string {
+	return systemNames[r.System]
+}
+
+//checks if the contents of files are available, imported logs contain only the changes of the commits
+func (r *Repository) HasContents() bool {
+	return r.System != GITLOG
+}
+
+//returns the oldest commit without parents
+func (r *Repository) FirstCommit() *Commit {
+	if roots := r.Roots(); len(roots) > 0 {
+		return roots[0]
+	}
 	return nil
 }
 
@@ -94,20 +294,73 @@ func (r *Repository) FindFileInCommit(fileId string, commitId string) *File {
 	return nil
 }
 
-//TODO validate directories
-func (r *Repository) checkWorkspace() {
-	if r.Workspace == "" {
-		//get hash from repo url
-		h := sha1.New()
-		io.WriteString(h, r.path)
-		dir := fmt.Sprintf("%x", h.Sum(nil))
+//detects the version control system of a local path or a remote url
+func detectSystem(path string) (int, error) {
 
-		//get current directory
-		cwd, err := os.Getwd()
-		if err != nil {
-			log.Fatal(cwd)
+	if info, err := os.Stat(path); err == nil {
+		//a single file is an export of "git log"
+		if info.IsDir() == false {
+			return GITLOG, nil
 		}
-		//TODO ensure file/dir handling
-		r.Workspace = filepath.Join(cwd, "workspace", dir)
+		return detectLocalSystem(path)
+	}
+
+	if scpPattern.MatchString(path) {
+		return GIT, nil
+	}
+
+	remote, err := url.Parse(path)
+	if err != nil || remote.Scheme == "" {
+		return 0, fmt.Errorf("%s: %s is neither a local path nor an url", ErrUnsupportedSystem, path)
 	}
+
+	switch remote.Scheme {
+	case "git", "git+ssh", "ssh+git":
+		return GIT, nil
+	case "svn", "svn+ssh":
+		return SVN, nil
+	case "file":
+		return detectLocalSystem(remote.Path)
+	case "http", "https", "ssh":
+		switch {
+		case strings.HasSuffix(remote.Path, ".git"),
+			remote.Host == "github.com", remote.Host == "gitlab.com",
+			remote.User != nil && remote.User.Username() == "git":
+			return GIT, nil
+		case strings.HasPrefix(remote.Host, "svn."), strings.Contains(remote.Path, "/svn/"):
+			return SVN, nil
+		case strings.HasPrefix(remote.Host, "hg."), strings.Contains(remote.Path, "/hg/"),
+			remote.User != nil && remote.User.Username() == "hg":
+			return HG, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s: unable to detect the type of %s", ErrUnsupportedSystem, path)
+}
+
+//detects the version control system of a local working copy or repository
+func detectLocalSystem(path string) (int, error) {
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(path, name))
+		return err == nil
+	}
+
+	switch {
+	//working copy (.git could also be a file for submodules and worktrees)
+	case exists(".git"):
+		return GIT, nil
+	//bare repository
+	case exists("HEAD") && exists("objects") && exists("refs"):
+		return GIT, nil
+	case exists(".hg"):
+		return HG, nil
+	case exists(".svn"):
+		return SVN, nil
+	//repository created by svnadmin
+	case exists("format") && exists("db"):
+		return SVN, nil
+	}
+
+	return 0, fmt.Errorf("%s: no repository found in %s", ErrUnsupportedSystem, path)
 }
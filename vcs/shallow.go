@@ -0,0 +1,80 @@
+package vcs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//branches and tags fetched into a clone of a previous run
+var cloneRefSpecs = []string{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"}
+
+//checks if remote git repositories are cloned with a limited history
+func isShallowClone() bool {
+	return CloneDepth > 0 || CloneSince.IsZero() == false
+}
+
+//clones a remote git repository (bare) with a limited history by the git command line client
+func cloneShallow(path string, workspace string) error {
+
+	args := []string{"clone", "--bare", "--quiet"}
+	if CloneDepth > 0 {
+		args = append(args, "--depth", strconv.Itoa(CloneDepth))
+	}
+	if CloneSince.IsZero() == false {
+		args = append(args, "--shallow-since", CloneSince.Format(time.RFC3339))
+	}
+	args = append(args, "--", path, workspace)
+
+	if _, err := runCommand("git", args...); err != nil {
+		return fmt.Errorf("unable to get git repository: %s", err)
+	}
+	return nil
+}
+
+/*
+updates a bare clone of a previous run by the git command line client, all
+branches and tags are fetched to the local refs (like the clone) and a shallow
+clone keeps its limited history
+*/
+func fetchClone(workspace string) error {
+
+	args := []string{"fetch", "--quiet", "--force", "--prune", "--update-head-ok"}
+	if CloneDepth > 0 {
+		args = append(args, "--depth", strconv.Itoa(CloneDepth))
+	}
+	if CloneSince.IsZero() == false {
+		args = append(args, "--shallow-since", CloneSince.Format(time.RFC3339))
+	}
+	args = append(args, "origin", cloneRefSpecs[0], cloneRefSpecs[1])
+
+	if _, err := runCommandIn(workspace, nil, "git", args...); err != nil {
+		return fmt.Errorf("unable to update git repository: %s", err)
+	}
+	return nil
+}
+
+//returns the boundary commits of a shallow repository, listed by the "shallow" file of the git directory
+func readShallowFile(gitDir string) (map[string]bool, error) {
+
+	boundaries := map[string]bool{}
+	file, err := os.Open(filepath.Join(gitDir, "shallow"))
+	if os.IsNotExist(err) {
+		return boundaries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			boundaries[id] = true
+		}
+	}
+	return boundaries, scanner.Err()
+}
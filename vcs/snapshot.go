@@ -0,0 +1,406 @@
+package vcs
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//version of the snapshot format, snapshots of other versions are ignored
+const SnapshotVersion = 7
+
+//connectors which are able to continue loading from a snapshot
+type resumableConnector interface {
+	//returns the loader for the content of a file, the source is set by the connector (e.g. "path@revision")
+	fileLoader(id string, source string) func() ([]byte, error)
+	//sets the already known model, known commits are not loaded again
+	resume(commits map[string]*Commit, developers map[string]*Developer, files map[string]*File)
+}
+
+/*
+persisted repository model (as loaded by the connector, before identities are
+merged and the window is selected) and additional data of other packages
+*/
+type Snapshot struct {
+	Version    int
+	Path       string
+	System     int
+	Config     string
+	Created    time.Time
+	Commits    []snapshotCommit
+	Developers []snapshotDeveloper
+	Files      []snapshotFile
+	//files excluded by their content, they are not part of the commits anymore
+	Excluded map[string]string
+	Data     map[string][]byte
+}
+
+type snapshotCommit struct {
+	Id        string
+	Date      time.Time
+	Message   string
+	Developer string
+	Committer string
+	CoAuthors []string
+	Files     map[string]string
+	Diffs     []snapshotParentDiff
+	Parents   []string
+	Boundary  bool
+	Subtrees  map[string]string
+}
+
+type snapshotParentDiff struct {
+	Parent        string
+	RemovedFiles  map[string]string
+	ChangedFiles  map[string]string
+	AddedFiles    map[string]string
+	MovedFiles    map[string]string
+	LineDiff      LineDiff
+	FileLines     map[string]LineDiff
+	Hunks         map[string][]*Hunk
+	ExistingFiles map[string]string
+}
+
+type snapshotDeveloper struct {
+	Id    string
+	Name  string
+	Email string
+}
+
+type snapshotFile struct {
+	Id      string
+	Size    int64
+	Source  string
+	Parents []string
+}
+
+/*
+settings which change the loaded model or the analyzed commits, a snapshot is
+only used with the same settings (rules of the .gitattributes are not covered)
+*/
+func snapshotConfig() string {
+	from, _ := splitRange(Revision)
+	lang := ""
+	if Filter != nil {
+		lang = Filter.Lang()
+	}
+	return fmt.Sprintf("lang=%s include=%s exclude=%s from=%s since=%s until=%s hunks=%t depth=%d shallow-since=%s imports=%s generated=%t",
+		lang, strings.Join(Paths.Include, ","), strings.Join(Paths.Exclude, ","),
+		from, Since.Format(time.RFC3339), Until.Format(time.RFC3339), KeepHunks,
+		CloneDepth, CloneSince.Format(time.RFC3339), ImportPolicy, DetectGenerated)
+}
+
+//creates a snapshot of the loaded commits and developers
+func newSnapshot(path string, system int, commits map[string]*Commit, developers map[string]*Developer) *Snapshot {
+
+	snapshot := &Snapshot{
+		Version: SnapshotVersion,
+		Path:    path,
+		System:  system,
+		Config:  snapshotConfig(),
+		Created: time.Now(),
+		Data:    map[string][]byte{},
+	}
+
+	//committers and co-authors without credit are kept for their commits
+	involved := map[string]*Developer{}
+	for id, dev := range developers {
+		involved[id] = dev
+	}
+	for _, commit := range commits {
+		for _, dev := range append(commit.Authors(), commit.Committer) {
+			if dev != nil {
+				involved[dev.Id] = dev
+			}
+		}
+	}
+	for _, dev := range involved {
+		snapshot.Developers = append(snapshot.Developers, snapshotDeveloper{dev.Id, dev.Name, dev.Email})
+	}
+
+	//collect all files including their previous versions
+	files := map[string]*File{}
+	var collect func(file *File)
+	collect = func(file *File) {
+		if _, exists := files[file.Id]; exists {
+			return
+		}
+		files[file.Id] = file
+		for _, parent := range file.Parents {
+			if parent != nil {
+				collect(parent)
+			}
+		}
+	}
+	addFiles := func(fileMap map[string]*File) map[string]string {
+		ids := map[string]string{}
+		for path, file := range fileMap {
+			if file != nil {
+				ids[path] = file.Id
+				collect(file)
+			}
+		}
+		return ids
+	}
+
+	for _, commit := range commits {
+		crt := snapshotCommit{
+			Id:        commit.Id,
+			Date:      commit.Date,
+			Message:   commit.Message,
+			Developer: commit.Developer.Id,
+			Files:     addFiles(commit.Files),
+			Boundary:  commit.Boundary,
+			Subtrees:  commit.Subtrees,
+		}
+		if commit.Committer != nil {
+			crt.Committer = commit.Committer.Id
+		}
+		for _, coAuthor := range commit.CoAuthors {
+			crt.CoAuthors = append(crt.CoAuthors, coAuthor.Id)
+		}
+		for _, diff := range commit.Diffs {
+			crt.Diffs = append(crt.Diffs, snapshotParentDiff{
+				Parent:        diff.Parent,
+				RemovedFiles:  addFiles(diff.RemovedFiles),
+				ChangedFiles:  addFiles(diff.ChangedFiles),
+				AddedFiles:    addFiles(diff.AddedFiles),
+				MovedFiles:    diff.MovedFiles,
+				LineDiff:      diff.LineDiff,
+				FileLines:     diff.FileLines,
+				Hunks:         diff.Hunks,
+				ExistingFiles: addFiles(diff.ExistingFiles),
+			})
+		}
+		for id := range commit.Parents {
+			crt.Parents = append(crt.Parents, id)
+		}
+		snapshot.Commits = append(snapshot.Commits, crt)
+	}
+
+	for _, file := range files {
+		crt := snapshotFile{Id: file.Id, Size: file.Size, Source: file.source}
+		for _, parent := range file.Parents {
+			if parent != nil {
+				crt.Parents = append(crt.Parents, parent.Id)
+			}
+		}
+		snapshot.Files = append(snapshot.Files, crt)
+	}
+
+	return snapshot
+}
+
+//reads a snapshot, missing files or snapshots of other versions are reported as error
+func LoadSnapshot(filename string) (*Snapshot, error) {
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	snapshot := &Snapshot{}
+	if err := gob.NewDecoder(file).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("unable to read snapshot %s: %s", filename, err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("snapshot %s has version %d, expected %d", filename, snapshot.Version, SnapshotVersion)
+	}
+	if snapshot.Data == nil {
+		snapshot.Data = map[string][]byte{}
+	}
+	return snapshot, nil
+}
+
+//writes the snapshot, the file is replaced atomically
+func (snapshot *Snapshot) Save(filename string) error {
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+
+	err = gob.NewEncoder(tmpFile).Encode(snapshot)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("unable to save snapshot %s: %s", filename, err)
+	}
+	return nil
+}
+
+//rebuilds the model of the snapshot, file contents are loaded by the connector
+func (snapshot *Snapshot) restore(connector resumableConnector) (map[string]*Commit, map[string]*Developer, map[string]*File) {
+
+	developers := map[string]*Developer{}
+	for _, crt := range snapshot.Developers {
+		developers[crt.Id] = NewDeveloper(crt.Id, crt.Email, crt.Name)
+	}
+
+	files := map[string]*File{}
+	for _, crt := range snapshot.Files {
+		files[crt.Id] = newFile(crt.Id, crt.Size, crt.Source, connector.fileLoader(crt.Id, crt.Source))
+	}
+	for _, crt := range snapshot.Files {
+		for _, id := range crt.Parents {
+			files[crt.Id].Parents = append(files[crt.Id].Parents, files[id])
+		}
+	}
+
+	fileMap := func(ids map[string]string) map[string]*File {
+		fileMap := map[string]*File{}
+		for path, id := range ids {
+			fileMap[path] = files[id]
+		}
+		return fileMap
+	}
+
+	commits := map[string]*Commit{}
+	for _, crt := range snapshot.Commits {
+		dev := developers[crt.Developer]
+		commit := NewCommit(crt.Id, crt.Message, crt.Date, dev)
+		commit.Files = fileMap(crt.Files)
+		commit.Boundary = crt.Boundary
+		if crt.Subtrees != nil {
+			commit.Subtrees = crt.Subtrees
+		}
+		if committer, exists := developers[crt.Committer]; exists {
+			commit.SetCommitter(committer)
+		}
+		for _, id := range crt.CoAuthors {
+			commit.AddCoAuthor(developers[id])
+		}
+		for _, diff := range crt.Diffs {
+			parentDiff := NewParentDiff(diff.Parent)
+			parentDiff.RemovedFiles = fileMap(diff.RemovedFiles)
+			parentDiff.ChangedFiles = fileMap(diff.ChangedFiles)
+			parentDiff.AddedFiles = fileMap(diff.AddedFiles)
+			parentDiff.ExistingFiles = fileMap(diff.ExistingFiles)
+			if diff.MovedFiles != nil {
+				parentDiff.MovedFiles = diff.MovedFiles
+			}
+			if diff.FileLines != nil {
+				parentDiff.FileLines = diff.FileLines
+			}
+			if diff.Hunks != nil {
+				parentDiff.Hunks = diff.Hunks
+			}
+			parentDiff.LineDiff = diff.LineDiff
+			commit.AddDiff(parentDiff)
+		}
+
+		commits[crt.Id] = commit
+		dev.Commits[crt.Id] = commit
+	}
+	for _, crt := range snapshot.Commits {
+		for _, id := range crt.Parents {
+			if parent, exists := commits[id]; exists {
+				commits[crt.Id].Parents[id] = parent
+				parent.Children[crt.Id] = commits[crt.Id]
+			}
+		}
+	}
+
+	return commits, developers, files
+}
+
+//loads the snapshot of the repository, if it exists and matches the current settings
+func (r *Repository) loadSnapshot() *Snapshot {
+
+	snapshot, err := LoadSnapshot(SnapshotFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		log.Printf("ignoring snapshot: %s", err)
+		return nil
+	}
+
+	switch {
+	case snapshot.Path != r.path || snapshot.System != r.System:
+		log.Printf("ignoring snapshot of %s, it belongs to another repository", snapshot.Path)
+		return nil
+	case snapshot.Config != snapshotConfig():
+		log.Printf("ignoring snapshot, it was created with other settings (%s)", snapshot.Config)
+		return nil
+	}
+
+	log.Printf("loaded snapshot with %d commits from %s", len(snapshot.Commits), snapshot.Created.Format(time.RFC3339))
+	return snapshot
+}
+
+/*
+removes restored commits which are not reachable from the selected revision
+anymore (e.g. rewritten history), returns the number of removed commits
+*/
+func (r *Repository) removeUnreachable(connector Connector) (int, error) {
+
+	_, revision := splitRange(Revision)
+	head, err := connector.Resolve(revision)
+	if err != nil {
+		return 0, err
+	}
+
+	reachable := map[string]bool{}
+	stack := []*Commit{r.Commits[head]}
+	for len(stack) > 0 {
+		commit := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if commit == nil || reachable[commit.Id] {
+			continue
+		}
+		reachable[commit.Id] = true
+		for _, parent := range commit.Parents {
+			stack = append(stack, parent)
+		}
+	}
+
+	removed := 0
+	for id, commit := range r.Commits {
+		if reachable[id] == false {
+			r.removeCommit(commit)
+			removed++
+		}
+	}
+	for id, dev := range r.Developers {
+		if dev.isEmpty() {
+			delete(r.Developers, id)
+		}
+	}
+	return removed, nil
+}
+
+//returns data of another package stored within the snapshot (nil if not available)
+func (r *Repository) SnapshotData(key string) []byte {
+	if r.snapshot == nil {
+		return nil
+	}
+	return r.snapshot.Data[key]
+}
+
+//stores data of another package within the snapshot
+func (r *Repository) SetSnapshotData(key string, data []byte) {
+	if r.snapshot != nil {
+		r.snapshot.Data[key] = data
+	}
+}
+
+//saves the snapshot of the repository to the snapshot file
+func (r *Repository) SaveSnapshot() error {
+	if r.snapshot == nil || SnapshotFile == "" {
+		return nil
+	}
+
+	log.Printf("saving snapshot with %d commits to %s", len(r.snapshot.Commits), SnapshotFile)
+	return r.snapshot.Save(SnapshotFile)
+}
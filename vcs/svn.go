@@ -0,0 +1,535 @@
+package vcs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//xml structure of "svn info --xml"
+type svnInfo struct {
+	URL  string `xml:"entry>url"`
+	Root string `xml:"entry>repository>root"`
+}
+
+//xml structure of "svn log --xml -v"
+type svnLog struct {
+	Entries []svnLogEntry `xml:"logentry"`
+}
+
+type svnLogEntry struct {
+	Revision int       `xml:"revision,attr"`
+	Author   string    `xml:"author"`
+	Date     string    `xml:"date"`
+	Message  string    `xml:"msg"`
+	Paths    []svnPath `xml:"paths>path"`
+}
+
+type svnPath struct {
+	Action       string `xml:"action,attr"`
+	Kind         string `xml:"kind,attr"`
+	CopyFromPath string `xml:"copyfrom-path,attr"`
+	CopyFromRev  int    `xml:"copyfrom-rev,attr"`
+	Path         string `xml:",chardata"`
+}
+
+//connector for subversion repositories, based on the svn command line client
+type SvnConnector struct {
+	url         string
+	root        string
+	prefix      string
+	storagePath string
+	commits     map[string]*Commit
+	developers  map[string]*Developer
+	files       map[string]*File
+	skipList
+	pathAttributes
+}
+
+//loads a subversion working copy or a local repository (e.g. created by svnadmin)
+func (c *SvnConnector) LoadLocal(path string, workspace string) error {
+
+	target := path
+	if _, err := os.Stat(filepath.Join(path, ".svn")); err != nil {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		target = "file://" + filepath.ToSlash(absPath)
+	}
+
+	if err := c.init(target, workspace); err != nil {
+		return err
+	}
+
+	log.Printf("opened local svn repo in %s", path)
+	return nil
+}
+
+//loads a repository by its url (svn://, http(s)://, file://)
+func (c *SvnConnector) LoadRemote(path string, workspace string) error {
+
+	if err := c.init(path, workspace); err != nil {
+		return err
+	}
+
+	log.Printf("opened remote svn repo %s", path)
+	return nil
+}
+
+func (c *SvnConnector) init(target string, workspace string) error {
+
+	out, err := runCommand("svn", "info", "--xml", target)
+	if err != nil {
+		return fmt.Errorf("unable to get svn repository: %s", err)
+	}
+
+	info := svnInfo{}
+	if err := xml.Unmarshal(out, &info); err != nil {
+		return fmt.Errorf("unable to read svn info: %s", err)
+	}
+
+	c.url = info.URL
+	c.root = info.Root
+	if c.prefix, err = url.PathUnescape(strings.TrimPrefix(info.URL, info.Root)); err != nil {
+		return err
+	}
+
+	c.storagePath = workspace
+	var fm os.FileMode = 0700
+	if err := os.MkdirAll(c.storagePath, fm); err != nil {
+		return &WorkspaceError{c.storagePath, err}
+	}
+
+	//the model could already be restored from a snapshot
+	if c.commits == nil {
+		c.developers = map[string]*Developer{}
+		c.commits = map[string]*Commit{}
+		c.files = map[string]*File{}
+	}
+
+	c.loadPathAttributes(c)
+
+	if err := c.fetchAll(); err != nil {
+		return err
+	}
+
+	log.Printf("loaded %d commits, %d delevopers and %d different files from svn repo",
+		len(c.commits), len(c.developers), len(c.files))
+
+	return nil
+}
+
+func (c *SvnConnector) Developers() map[string]*Developer {
+	return c.developers
+}
+
+func (c *SvnConnector) Commits() map[string]*Commit {
+	return c.commits
+}
+
+func (c *SvnConnector) resume(commits map[string]*Commit, developers map[string]*Developer, files map[string]*File) {
+	c.commits = commits
+	c.developers = developers
+	c.files = files
+}
+
+func (c *SvnConnector) fetchAll() error {
+
+	_, revision := splitRange(Revision)
+	out, err := runCommand("svn", "log", "--xml", "-v", "-r", "1:"+svnRevision(revision), c.url)
+	if err != nil {
+		return err
+	}
+
+	svnLog := svnLog{}
+	if err := xml.Unmarshal(out, &svnLog); err != nil {
+		return fmt.Errorf("unable to read svn log: %s", err)
+	}
+
+	//svn history is linear, every revision is the parent of the following one
+	var parent *Commit
+	for _, entry := range svnLog.Entries {
+		//already known from a snapshot
+		if commit, exists := c.commits[strconv.Itoa(entry.Revision)]; exists {
+			parent = commit
+			continue
+		}
+
+		parentId := ""
+		if parent != nil {
+			parentId = parent.Id
+		}
+		commit, err := c.createCommit(entry, parentId)
+		if err != nil {
+			return err
+		}
+
+		if parent != nil {
+			commit.Parents[parent.Id] = parent
+			parent.Children[commit.Id] = commit
+		}
+		parent = commit
+	}
+
+	return nil
+}
+
+//returns the latest revision (up to the given one) that changed the loaded url
+func (c *SvnConnector) Resolve(revision string) (string, error) {
+
+	out, err := runCommand("svn", "log", "--xml", "-l", "1", "-r", svnRevision(revision)+":1", c.url)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve revision %s: %s", revision, err)
+	}
+
+	svnLog := svnLog{}
+	if err := xml.Unmarshal(out, &svnLog); err != nil || len(svnLog.Entries) == 0 {
+		return "", fmt.Errorf("unable to resolve revision %s", revision)
+	}
+	return strconv.Itoa(svnLog.Entries[0].Revision), nil
+}
+
+/*
+returns the tags of the standard layout (a tags directory next to trunk), a tag
+points to the latest loaded revision up to the revision it was copied from
+*/
+func (c *SvnConnector) loadTags() ([]*Tag, error) {
+
+	if strings.HasSuffix(c.prefix, "/trunk") == false {
+		return []*Tag{}, nil
+	}
+	tagsPath := strings.TrimSuffix(c.prefix, "/trunk") + "/tags"
+
+	//the tags directory is optional
+	_, revision := splitRange(Revision)
+	out, err := runCommand("svn", "log", "--xml", "-v", "-r", "1:"+svnRevision(revision), c.fileURL(tagsPath))
+	if err != nil {
+		return []*Tag{}, nil
+	}
+
+	svnLog := svnLog{}
+	if err := xml.Unmarshal(out, &svnLog); err != nil {
+		return nil, fmt.Errorf("unable to read svn log of tags: %s", err)
+	}
+
+	revisions := []int{}
+	for id := range c.commits {
+		if n, err := strconv.Atoi(id); err == nil {
+			revisions = append(revisions, n)
+		}
+	}
+	sort.Ints(revisions)
+
+	tags := map[string]*Tag{}
+	for _, entry := range svnLog.Entries {
+		date, err := time.Parse(time.RFC3339Nano, entry.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date in revision %d: %s", entry.Revision, err)
+		}
+
+		for _, changed := range entry.Paths {
+			name := strings.TrimPrefix(changed.Path, tagsPath+"/")
+			if name == changed.Path || strings.Contains(name, "/") {
+				continue
+			}
+
+			switch {
+			case changed.Action == "D":
+				delete(tags, name)
+			case changed.CopyFromRev > 0:
+				if n := sort.SearchInts(revisions, changed.CopyFromRev+1) - 1; n >= 0 {
+					tags[name] = &Tag{name, c.commits[strconv.Itoa(revisions[n])], date}
+				}
+			}
+		}
+	}
+
+	result := []*Tag{}
+	for _, tag := range tags {
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+//reads a file of the analyzed revision
+func (c *SvnConnector) ReadFile(path string) ([]byte, error) {
+	_, revision := splitRange(Revision)
+	return runCommand("svn", "cat", fmt.Sprintf("%s@%s", c.fileURL(c.prefix+"/"+path), svnRevision(revision)))
+}
+
+//returns the svn revision, HEAD if empty
+func svnRevision(revision string) string {
+	if revision == "" {
+		return "HEAD"
+	}
+	return revision
+}
+
+//creates an internal commit object based on a svn log entry and the previous revision
+func (c *SvnConnector) createCommit(entry svnLogEntry, parentId string) (*Commit, error) {
+
+	dev, exists := c.developers[entry.Author]
+	if !exists {
+		dev = NewDeveloper(entry.Author, "", entry.Author)
+		c.developers[entry.Author] = dev
+	}
+
+	date, err := time.Parse(time.RFC3339Nano, entry.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date in revision %d: %s", entry.Revision, err)
+	}
+
+	id := strconv.Itoa(entry.Revision)
+	commit := NewCommit(id, entry.Message, date, dev)
+
+	c.commits[id] = commit
+	dev.Commits[id] = commit
+
+	//svn does not distinguish author and committer
+	commit.SetCommitter(dev)
+	commit.readCoAuthors(c.developers)
+
+	diff := NewParentDiff(parentId)
+	c.loadChanges(commit, diff, entry)
+	c.loadLineDiff(commit, diff)
+
+	commit.AddDiff(diff)
+	return commit, nil
+}
+
+//adds the changed files of a revision to the commit and its diff, files which cannot be read are skipped
+func (c *SvnConnector) loadChanges(commit *Commit, diff *ParentDiff, entry svnLogEntry) {
+
+	changes, err := c.expandChanges(entry)
+	if err != nil {
+		c.skip(&DiffError{Commit: commit.Id, Parent: diff.Parent, Err: err})
+		return
+	}
+
+	//a move is a copy of a file that was deleted within the same revision
+	deleted := map[string]bool{}
+	for _, change := range changes {
+		if change.Action == "D" {
+			deleted[change.Path] = true
+		}
+	}
+	moved := map[string]bool{}
+
+	paths := []string{}
+	for path := range changes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		change := changes[path]
+		if change.Action == "D" {
+			continue
+		}
+
+		relPath := c.relativePath(path)
+		file, err := c.loadFile(path, entry.Revision)
+		if err != nil {
+			c.skip(err)
+			continue
+		}
+		commit.Files[relPath] = file
+
+		switch {
+		case change.CopyFromPath != "" && deleted[change.CopyFromPath] && c.inPrefix(change.CopyFromPath):
+			oldFile, err := c.loadFile(change.CopyFromPath, change.CopyFromRev)
+			if err != nil {
+				c.skip(err)
+				continue
+			}
+			moved[change.CopyFromPath] = true
+			diff.MovedFiles[c.relativePath(change.CopyFromPath)] = relPath
+
+			if oldFile.Id != file.Id {
+				diff.ChangedFiles[relPath] = file
+				file.Parents = append(file.Parents, oldFile)
+			}
+
+		case change.Action == "A":
+			diff.AddedFiles[relPath] = file
+
+		default:
+			oldFile, err := c.loadFile(path, entry.Revision-1)
+			if err != nil {
+				c.skip(err)
+				continue
+			}
+			diff.ChangedFiles[relPath] = file
+			file.Parents = append(file.Parents, oldFile)
+		}
+	}
+
+	for path := range deleted {
+		if moved[path] == false {
+			oldFile, err := c.loadFile(path, entry.Revision-1)
+			if err != nil {
+				c.skip(err)
+				continue
+			}
+			diff.RemovedFiles[c.relativePath(path)] = oldFile
+		}
+	}
+}
+
+/*
+returns all changed files of a revision, changes on directories
+(copies, moves and deletions) are resolved to the contained files
+*/
+func (c *SvnConnector) expandChanges(entry svnLogEntry) (map[string]svnPath, error) {
+
+	changes := map[string]svnPath{}
+
+	for _, change := range entry.Paths {
+		if change.Kind != "dir" || c.inPrefix(change.Path) == false {
+			continue
+		}
+
+		var files []string
+		var err error
+		switch {
+		case change.Action == "D":
+			files, err = c.listFiles(change.Path, entry.Revision-1)
+		case change.CopyFromPath != "":
+			files, err = c.listFiles(change.CopyFromPath, change.CopyFromRev)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			fileChange := svnPath{Action: change.Action, Kind: "file", Path: change.Path + "/" + file}
+			if change.Action == "R" {
+				fileChange.Action = "A"
+			}
+			if change.CopyFromPath != "" {
+				fileChange.CopyFromPath = change.CopyFromPath + "/" + file
+				fileChange.CopyFromRev = change.CopyFromRev
+			}
+			if c.validPath(c.relativePath(fileChange.Path)) {
+				changes[fileChange.Path] = fileChange
+			}
+		}
+	}
+
+	for _, change := range entry.Paths {
+		if change.Kind == "dir" || c.inPrefix(change.Path) == false || c.validPath(c.relativePath(change.Path)) == false {
+			continue
+		}
+
+		//keep copy information of directory changes
+		if _, exists := changes[change.Path]; exists && change.Action == "M" {
+			continue
+		}
+		changes[change.Path] = change
+	}
+
+	return changes, nil
+}
+
+//counts the added and removed lines of a revision, the lines are skipped if the diff cannot be read
+func (c *SvnConnector) loadLineDiff(commit *Commit, diff *ParentDiff) {
+
+	out, err := runCommand("svn", "diff", "-c", commit.Id, c.url)
+	if err != nil {
+		c.skip(&DiffError{Commit: commit.Id, Parent: diff.Parent, Err: err})
+		return
+	}
+
+	patches, err := parseUnifiedDiff(bytes.NewReader(out))
+	if err != nil {
+		c.skip(&DiffError{Commit: commit.Id, Parent: diff.Parent, Err: err})
+		return
+	}
+
+	for _, patch := range patches {
+		if c.validPath(patch.Path()) {
+			diff.AddLines(patch.Path(), patch.LineDiff)
+			for _, hunk := range patch.Hunks {
+				diff.AddHunk(hunk)
+			}
+		}
+	}
+}
+
+//loads the content of a file at the given revision
+func (c *SvnConnector) loadFile(path string, revision int) (*File, error) {
+
+	content, err := runCommand("svn", "cat", fmt.Sprintf("%s@%d", c.fileURL(path), revision))
+	if err != nil {
+		return nil, &BlobError{fmt.Sprintf("%s@%d", path, revision), err}
+	}
+
+	id := blobId(content)
+	if file, exists := c.files[id]; exists {
+		return file, nil
+	}
+
+	//the content is needed for the id anyway, so it is cached right away
+	Blobs.Put(id, content)
+	source := fmt.Sprintf("%s@%d", path, revision)
+	file := newFile(id, int64(len(content)), source, c.fileLoader(id, source))
+	c.files[id] = file
+	return file, nil
+}
+
+//files are loaded by their path and revision ("path@revision")
+func (c *SvnConnector) fileLoader(id string, source string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		i := strings.LastIndex(source, "@")
+		if i < 0 {
+			return nil, &BlobError{id, fmt.Errorf("unknown source %q", source)}
+		}
+		content, err := runCommand("svn", "cat", c.fileURL(source[:i])+source[i:])
+		if err != nil {
+			return nil, &BlobError{id, err}
+		}
+		return content, nil
+	}
+}
+
+//lists all files within a directory at the given revision
+func (c *SvnConnector) listFiles(path string, revision int) ([]string, error) {
+
+	out, err := runCommand("svn", "list", "-R", fmt.Sprintf("%s@%d", c.fileURL(path), revision))
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && strings.HasSuffix(line, "/") == false {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+//returns the escaped url for a path within the repository
+func (c *SvnConnector) fileURL(path string) string {
+	return c.root + (&url.URL{Path: path}).EscapedPath()
+}
+
+//checks if the path is located below the loaded url
+func (c *SvnConnector) inPrefix(path string) bool {
+	return c.prefix == "" || path == c.prefix || strings.HasPrefix(path, c.prefix+"/")
+}
+
+//returns the path relative to the loaded url
+func (c *SvnConnector) relativePath(path string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(path, c.prefix), "/")
+}
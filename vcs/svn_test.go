@@ -0,0 +1,206 @@
+package vcs
+
+import (
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//output of "svn log --xml -v" for a revision that moves and changes a file
+const svnTestLog = `<?xml version="1.0" encoding="UTF-8"?>
+<log>
+<logentry revision="3">
+<author>alice</author>
+<date>2024-03-01T10:00:00.123456Z</date>
+<paths>
+<path action="D" prop-mods="false" text-mods="false" kind="file">/trunk/b.txt</path>
+<path action="A" prop-mods="false" text-mods="true" kind="file" copyfrom-path="/trunk/b.txt" copyfrom-rev="2">/trunk/c.txt</path>
+</paths>
+<msg>move b</msg>
+</logentry>
+</log>`
+
+//output of "svn diff -c 4" for a revision that removes a file and changes another one
+const svnTestDiff = `Index: a.txt
+===================================================================
+--- a.txt	(revision 3)
++++ a.txt	(nonexistent)
+@@ -1,3 +0,0 @@
+-one
+-two
+-three
+Index: c.txt
+===================================================================
+--- c.txt	(revision 3)
++++ c.txt	(revision 4)
+@@ -1 +1,2 @@
+ b
++--- not a header
+`
+
+func TestSvnLog(t *testing.T) {
+
+	log := svnLog{}
+	if err := xml.Unmarshal([]byte(svnTestLog), &log); err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(log.Entries))
+	}
+
+	entry := log.Entries[0]
+	if entry.Revision != 3 || entry.Author != "alice" || entry.Message != "move b" || len(entry.Paths) != 2 {
+		t.Fatalf("unexpected log entry %+v", entry)
+	}
+	moved := entry.Paths[1]
+	if moved.Action != "A" || moved.Kind != "file" || moved.Path != "/trunk/c.txt" ||
+		moved.CopyFromPath != "/trunk/b.txt" || moved.CopyFromRev != 2 {
+		t.Errorf("unexpected copied path %+v", moved)
+	}
+
+	c := &SvnConnector{root: "file:///tmp/repo", prefix: "/trunk"}
+	if c.inPrefix("/trunk/b.txt") == false || c.inPrefix("/trunk2/b.txt") {
+		t.Error("wrong prefix check")
+	}
+	if path := c.relativePath(moved.Path); path != "c.txt" {
+		t.Errorf("expected relative path c.txt, got %s", path)
+	}
+	if url := c.fileURL("/trunk/a b.txt"); url != "file:///tmp/repo/trunk/a%20b.txt" {
+		t.Errorf("unexpected file url %s", url)
+	}
+}
+
+func TestSvnDiff(t *testing.T) {
+
+	patches, err := parseUnifiedDiff(strings.NewReader(svnTestDiff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 2 {
+		t.Fatalf("expected 2 patches, got %d", len(patches))
+	}
+
+	expected := map[string]LineDiff{"a.txt": {0, 3}, "c.txt": {1, 0}}
+	for _, patch := range patches {
+		if patch.LineDiff != expected[patch.Path()] {
+			t.Errorf("expected %v lines for %s, got %v", expected[patch.Path()], patch.Path(), patch.LineDiff)
+		}
+	}
+}
+
+/*
+creates a repository with svnadmin and commits four revisions: two files are
+added, one of them is changed, the other one is moved and the first one is
+removed at last
+*/
+func TestSvnConnector(t *testing.T) {
+
+	for _, name := range []string{"svn", "svnadmin"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s is not installed", name)
+		}
+	}
+
+	Filter = PassThroughFilter{}
+	WorkspaceRoot = t.TempDir()
+	defer func() { WorkspaceRoot = "" }()
+
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	workingCopy := filepath.Join(t.TempDir(), "wc")
+	run := func(dir string, name string, args ...string) {
+		if _, err := runCommandIn(dir, nil, name, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(name string, content string) {
+		if err := os.WriteFile(filepath.Join(workingCopy, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(message string) {
+		run(workingCopy, "svn", "commit", "-q", "--username", "alice", "-m", message)
+	}
+
+	run("", "svnadmin", "create", repoPath)
+	run("", "svn", "checkout", "-q", "file://"+filepath.ToSlash(repoPath), workingCopy)
+
+	write("a.txt", "one\ntwo\n")
+	write("b.txt", "b\n")
+	run(workingCopy, "svn", "add", "-q", "a.txt", "b.txt")
+	commit("add a and b")
+
+	write("a.txt", "one\ntwo\nthree\n")
+	commit("change a")
+
+	run(workingCopy, "svn", "mv", "-q", "b.txt", "c.txt")
+	commit("move b")
+
+	run(workingCopy, "svn", "rm", "-q", "a.txt")
+	commit("remove a")
+
+	repo, err := NewRepository(repoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Commits) != 4 {
+		t.Fatalf("expected 4 commits, got %d", len(repo.Commits))
+	}
+	if len(repo.Skipped) > 0 {
+		t.Fatalf("unexpected skipped objects %v", repo.Skipped)
+	}
+
+	r1, r2, r3, r4 := repo.Commits["1"], repo.Commits["2"], repo.Commits["3"], repo.Commits["4"]
+
+	if len(r1.AddedFiles) != 2 || r1.AddedFiles["a.txt"] == nil || r1.AddedFiles["b.txt"] == nil {
+		t.Errorf("expected a.txt and b.txt to be added in r1, got %v", r1.AddedFiles)
+	}
+	if r1.LineDiff != (LineDiff{3, 0}) {
+		t.Errorf("expected 3 added lines in r1, got %v", r1.LineDiff)
+	}
+
+	if len(r2.ChangedFiles) != 1 || r2.ChangedFiles["a.txt"] == nil {
+		t.Errorf("expected a.txt to be changed in r2, got %v", r2.ChangedFiles)
+	} else if parents := r2.ChangedFiles["a.txt"].Parents; len(parents) != 1 || parents[0] != r1.Files["a.txt"] {
+		t.Errorf("expected the version of r1 as parent of a.txt, got %v", parents)
+	}
+	if r2.LineDiff != (LineDiff{1, 0}) {
+		t.Errorf("expected 1 added line in r2, got %v", r2.LineDiff)
+	}
+
+	if len(r3.MovedFiles) != 1 || r3.MovedFiles["b.txt"] != "c.txt" {
+		t.Errorf("expected b.txt to be moved to c.txt in r3, got %v", r3.MovedFiles)
+	}
+	if len(r3.AddedFiles) > 0 || len(r3.ChangedFiles) > 0 || len(r3.RemovedFiles) > 0 {
+		t.Errorf("expected only a move in r3, got %v %v %v", r3.AddedFiles, r3.ChangedFiles, r3.RemovedFiles)
+	}
+	if content, err := r3.Files["c.txt"].Content(); err != nil || content != "b\n" {
+		t.Errorf("unexpected content of c.txt %q (%v)", content, err)
+	}
+
+	if len(r4.RemovedFiles) != 1 || r4.RemovedFiles["a.txt"] != r2.Files["a.txt"] {
+		t.Errorf("expected the version of r2 of a.txt to be removed in r4, got %v", r4.RemovedFiles)
+	}
+	if r4.LineDiff != (LineDiff{0, 3}) {
+		t.Errorf("expected 3 removed lines in r4, got %v", r4.LineDiff)
+	}
+
+	//linear history
+	if len(r1.Parents) > 0 {
+		t.Errorf("expected r1 to be the root, got parents %v", r1.Parents)
+	}
+	for _, link := range [][2]*Commit{{r1, r2}, {r2, r3}, {r3, r4}} {
+		parent, child := link[0], link[1]
+		if len(child.Parents) != 1 || child.Parents[parent.Id] != parent || parent.Children[child.Id] != child {
+			t.Errorf("expected r%s to be the only parent of r%s", parent.Id, child.Id)
+		}
+	}
+	if len(r4.Children) > 0 {
+		t.Errorf("expected r4 to be the head, got children %v", r4.Children)
+	}
+	if head := repo.Head(); head != r4 {
+		t.Errorf("expected r4 as head, got %v", head)
+	}
+}
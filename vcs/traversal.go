@@ -0,0 +1,156 @@
+package vcs
+
+import (
+	"sort"
+)
+
+//orders commits by date, commits of the same date by id
+type commitsByDate []*Commit
+
+func (s commitsByDate) Len() int      { return len(s) }
+func (s commitsByDate) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s commitsByDate) Less(i, j int) bool {
+	if s[i].Date.Equal(s[j].Date) {
+		return s[i].Id < s[j].Id
+	}
+	return s[i].Date.Before(s[j].Date)
+}
+
+//returns the parents in the order of the vcs (first parent first)
+func (c *Commit) OrderedParents() []*Commit {
+
+	parents := []*Commit{}
+	added := map[string]bool{}
+	for _, diff := range c.Diffs {
+		if parent, exists := c.Parents[diff.Parent]; exists && added[parent.Id] == false {
+			parents = append(parents, parent)
+			added[parent.Id] = true
+		}
+	}
+
+	//parents without a diff (e.g. of a repository built by hand) are ordered by date
+	others := commitsByDate{}
+	for id, parent := range c.Parents {
+		if added[id] == false {
+			others = append(others, parent)
+		}
+	}
+	sort.Sort(others)
+
+	return append(parents, others...)
+}
+
+//returns the first parent of the commit, nil for root commits
+func (c *Commit) FirstParent() *Commit {
+	if parents := c.OrderedParents(); len(parents) > 0 {
+		return parents[0]
+	}
+	return nil
+}
+
+//returns all commits ordered by date (oldest first)
+func (r *Repository) DateOrder() []*Commit {
+	commits := commitsByDate{}
+	for _, commit := range r.Commits {
+		commits = append(commits, commit)
+	}
+	sort.Sort(commits)
+	return commits
+}
+
+//returns all commits without parents ordered by date
+func (r *Repository) Roots() []*Commit {
+	roots := commitsByDate{}
+	for _, commit := range r.Commits {
+		if len(commit.Parents) == 0 {
+			roots = append(roots, commit)
+		}
+	}
+	sort.Sort(roots)
+	return roots
+}
+
+//returns all commits without children ordered by date
+func (r *Repository) Heads() []*Commit {
+	heads := commitsByDate{}
+	for _, commit := range r.Commits {
+		if len(commit.Children) == 0 {
+			heads = append(heads, commit)
+		}
+	}
+	sort.Sort(heads)
+	return heads
+}
+
+//returns the latest commit without children, nil for empty repositories
+func (r *Repository) Head() *Commit {
+	if heads := r.Heads(); len(heads) > 0 {
+		return heads[len(heads)-1]
+	}
+	return nil
+}
+
+/*
+returns all commits in topological order: every commit is listed after all of
+its parents, independent commits are ordered by date
+*/
+func (r *Repository) TopologicalOrder() []*Commit {
+	return topologicalOrder(r.Commits)
+}
+
+//orders a set of commits topologically, parents outside of the set are ignored
+func topologicalOrder(commits map[string]*Commit) []*Commit {
+
+	pending := map[string]int{}
+	ready := commitsByDate{}
+	for id, commit := range commits {
+		for parentId := range commit.Parents {
+			if _, exists := commits[parentId]; exists {
+				pending[id]++
+			}
+		}
+		if pending[id] == 0 {
+			ready = append(ready, commit)
+		}
+	}
+	sort.Sort(ready)
+
+	ordered := make([]*Commit, 0, len(commits))
+	for len(ready) > 0 {
+		commit := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, commit)
+
+		children := commitsByDate{}
+		for id, child := range commit.Children {
+			if _, exists := commits[id]; !exists {
+				continue
+			}
+			if pending[id]--; pending[id] == 0 {
+				children = append(children, child)
+			}
+		}
+
+		//keep the ready commits ordered by date
+		if len(children) > 0 {
+			ready = append(ready, children...)
+			sort.Sort(ready)
+		}
+	}
+
+	return ordered
+}
+
+//returns the commits of the first parent chain of a commit (oldest first)
+func (r *Repository) FirstParentChain(head *Commit) []*Commit {
+
+	chain := []*Commit{}
+	for commit := head; commit != nil; commit = commit.FirstParent() {
+		chain = append(chain, commit)
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
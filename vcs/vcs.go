@@ -1,3 +1,110 @@
 package vcs
 
+import (
+	"strings"
+	"time"
+)
+
 var Filter LanguageFilter
+
+//policies for crediting the changes of merge commits
+const (
+	//changes compared to all parents
+	MergeAll = "all"
+	//no changes at all
+	MergeIgnore = "ignore"
+	//changes compared to the first parent (the branch the merge was done on)
+	MergeFirstParent = "first-parent"
+	//only changes which differ from all parents (e.g. conflict resolutions)
+	MergeConflicts = "conflicts"
+)
+
+//selected policy for merge commits
+var MergePolicy = MergeAll
+
+//policies for crediting the changes of a commit to its developers
+const (
+	//the author gets the complete credit
+	CreditAuthor = "author"
+	//the committer gets the complete credit
+	CreditCommitter = "committer"
+	//the author and all co-authors share the credit equally
+	CreditSplit = "split"
+	//the author and all co-authors get the complete credit
+	CreditShared = "shared"
+)
+
+//selected policy for crediting commits
+var CreditPolicy = CreditAuthor
+
+//policies for code imported by git submodules and subtree merges
+const (
+	//subtree merges are credited like other merges, submodules are only listed
+	ImportInclude = "include"
+	//files imported by subtree merges are not credited and the history of the subtree is not loaded
+	ImportSkip = "skip"
+	//like skip, but submodules are loaded as separate repositories
+	ImportRecurse = "recurse"
+)
+
+//selected policy for imported code
+var ImportPolicy = ImportInclude
+
+//selected backend for git repositories (LibGit2 or GoGit), empty for the default
+var GitBackend string
+
+//branch, tag or commit to analyze, only commits reachable from it are loaded (HEAD if empty),
+//for a range "A..B" commits reachable from A are excluded
+var Revision string
+
+//time window of the analyzed commits (ignored if zero)
+var Since, Until time.Time
+
+//limits the history of cloned remote git repositories (shallow clone) by
+//number of commits and by date (ignored if zero)
+var CloneDepth int
+var CloneSince time.Time
+
+//include and exclude rules for file paths
+var Paths = &PathFilter{}
+
+//exclude binary, generated and minified files by their content
+var DetectGenerated = true
+
+//directory for the workspaces of the analyzed repositories (./workspace if empty)
+var WorkspaceRoot string
+
+//number of parallel workers for diffing commits of the libgit2 connector (number of cpus if not set)
+var Workers int
+
+//keep the changed lines (hunks) of all diffs, needs much more memory
+var KeepHunks bool
+
+//file of the snapshot for incremental analysis (disabled if empty)
+var SnapshotFile string
+
+//cache for file contents (64 MB by default)
+var Blobs = NewBlobCache(64 << 20)
+
+//store cached file contents compressed within the workspace
+var DiskCache bool
+
+//merge developer identities by the .mailmap of the repository
+var UseMailmap = true
+
+//additional file with identity rules in .mailmap format
+var AliasFile string
+
+//merge developers with the same name
+var MergeByName bool
+
+//merge developers by their normalized email (lower case, without "+tag")
+var NormalizeEmails bool
+
+//splits a revision range "A..B" into its bounds, a single revision has no lower bound
+func splitRange(revision string) (from string, to string) {
+	if i := strings.Index(revision, ".."); i >= 0 {
+		return revision[:i], revision[i+2:]
+	}
+	return "", revision
+}
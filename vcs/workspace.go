@@ -0,0 +1,183 @@
+package vcs
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//file within a workspace, containing the path of the analyzed repository
+const workspaceInfoFile = "scabov.info"
+
+//cached workspace of an analyzed repository
+type Workspace struct {
+	Path     string
+	Source   string
+	Size     int64
+	LastUsed time.Time
+}
+
+func (w *Workspace) String() string {
+	return fmt.Sprintf("%s (%s, %.1f MB, last used %s)",
+		w.Path, w.Source, float64(w.Size)/(1<<20), w.LastUsed.Format("2006-01-02 15:04"))
+}
+
+//returns the absolute directory of all workspaces
+func workspaceRoot() (string, error) {
+	if WorkspaceRoot != "" {
+		return filepath.Abs(WorkspaceRoot)
+	}
+
+	//get current directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cwd, "workspace"), nil
+}
+
+//sets the workspace of the repository and ensures the root directory is writable
+func (r *Repository) checkWorkspace() error {
+	if r.Workspace == "" {
+		root, err := workspaceRoot()
+		if err != nil {
+			return err
+		}
+
+		var fm os.FileMode = 0700
+		if err := os.MkdirAll(root, fm); err != nil {
+			return &WorkspaceError{root, err}
+		}
+
+		//get hash from repo url
+		h := sha1.New()
+		io.WriteString(h, r.path)
+		dir := fmt.Sprintf("%x", h.Sum(nil))
+
+		r.Workspace = filepath.Join(root, dir)
+	}
+	return nil
+}
+
+//stores the source of the workspace, also used as marker for the last usage
+func (r *Repository) writeWorkspaceInfo() error {
+	var fm os.FileMode = 0700
+	if err := os.MkdirAll(r.Workspace, fm); err != nil {
+		return &WorkspaceError{r.Workspace, err}
+	}
+
+	var perm os.FileMode = 0600
+	infoFile := filepath.Join(r.Workspace, workspaceInfoFile)
+	if err := ioutil.WriteFile(infoFile, []byte(r.path+"\n"), perm); err != nil {
+		return &WorkspaceError{infoFile, err}
+	}
+	return nil
+}
+
+//checks if the workspace was used by a previous run, the info file is only written after the repository was loaded
+func hasWorkspaceInfo(workspace string) bool {
+	info, err := os.Stat(filepath.Join(workspace, workspaceInfoFile))
+	return err == nil && info.IsDir() == false
+}
+
+//deletes the workspace of the repository (clones, cached files, ...) and of its loaded submodules
+func (r *Repository) Cleanup() error {
+	for _, submodule := range r.Submodules {
+		if submodule.Repository != nil {
+			if err := submodule.Repository.Cleanup(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.Workspace == "" {
+		return nil
+	}
+
+	log.Printf("deleting workspace %s", r.Workspace)
+	if err := os.RemoveAll(r.Workspace); err != nil {
+		return err
+	}
+	r.Workspace = ""
+	return nil
+}
+
+/*
+lists all workspaces below the workspace root, least recently used first, only
+directories with a workspace info file are workspaces (the root could contain
+other directories, e.g. if it is the home directory)
+*/
+func ListWorkspaces() ([]*Workspace, error) {
+
+	root, err := workspaceRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(root)
+	if os.IsNotExist(err) {
+		return []*Workspace{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	workspaces := []*Workspace{}
+	for _, entry := range entries {
+		if entry.IsDir() == false {
+			continue
+		}
+
+		workspace := &Workspace{Path: filepath.Join(root, entry.Name())}
+		infoFile := filepath.Join(workspace.Path, workspaceInfoFile)
+		info, err := os.Stat(infoFile)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		workspace.LastUsed = info.ModTime()
+		if content, err := ioutil.ReadFile(infoFile); err == nil {
+			workspace.Source = strings.TrimSpace(string(content))
+		}
+
+		filepath.Walk(workspace.Path, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.IsDir() == false {
+				workspace.Size += info.Size()
+			}
+			return nil
+		})
+
+		workspaces = append(workspaces, workspace)
+	}
+
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].LastUsed.Before(workspaces[j].LastUsed)
+	})
+
+	return workspaces, nil
+}
+
+//deletes all workspaces that were not used within the given duration
+func PruneWorkspaces(maxAge time.Duration) ([]*Workspace, error) {
+
+	workspaces, err := ListWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := []*Workspace{}
+	for _, workspace := range workspaces {
+		if time.Since(workspace.LastUsed) > maxAge {
+			if err := os.RemoveAll(workspace.Path); err != nil {
+				return pruned, err
+			}
+			pruned = append(pruned, workspace)
+		}
+	}
+	return pruned, nil
+}
@@ -0,0 +1,101 @@
+package vcs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+/*
+loads a remote repository three times with the same workspace: the second run
+fetches a new commit into the clone of the first run and continues with its
+snapshot, the third run clones again, because the clone was corrupted
+*/
+func TestRemoteWorkspace(t *testing.T) {
+
+	Filter = PassThroughFilter{}
+	GitBackend = GoGit
+	WorkspaceRoot = t.TempDir()
+	SnapshotFile = filepath.Join(t.TempDir(), "snapshot")
+	defer func() { GitBackend, WorkspaceRoot, SnapshotFile = "", "", "" }()
+
+	origin := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = origin
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Alice", "GIT_AUTHOR_EMAIL=alice@example.com",
+			"GIT_COMMITTER_NAME=Alice", "GIT_COMMITTER_EMAIL=alice@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %s", args, out)
+		}
+	}
+	commit := func(name string, content string) {
+		if err := os.WriteFile(filepath.Join(origin, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", name)
+		git("commit", "-q", "-m", "add "+name)
+	}
+
+	git("init", "-q", "-b", "main")
+	commit("a.txt", "a\n")
+	commit("b.txt", "b\n")
+	url := "file://" + filepath.ToSlash(origin)
+
+	repo, err := NewRepository(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Commits) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(repo.Commits))
+	}
+	if err := repo.SaveSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	//a marker within the workspace is only kept, if the clone is reused
+	marker := filepath.Join(repo.Workspace, "marker")
+	if err := os.WriteFile(marker, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	commit("c.txt", "c\n")
+	updated, err := NewRepository(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Workspace != repo.Workspace {
+		t.Errorf("expected the workspace %s, got %s", repo.Workspace, updated.Workspace)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected the clone to be reused: %s", err)
+	}
+	if len(updated.Commits) != 3 {
+		t.Fatalf("expected 3 commits, got %d", len(updated.Commits))
+	}
+	head := updated.Head()
+	if head == nil || head.AddedFiles["c.txt"] == nil || len(head.Parents) != 1 {
+		t.Errorf("expected the fetched commit as head, got %v", head)
+	}
+	for id := range repo.Commits {
+		if updated.Commits[id] == nil {
+			t.Errorf("commit %s of the first run is missing", id)
+		}
+	}
+
+	//a corrupt clone is replaced
+	if err := os.WriteFile(filepath.Join(repo.Workspace, "HEAD"), []byte("corrupt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cloned, err := NewRepository(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); os.IsNotExist(err) == false {
+		t.Errorf("expected the corrupt clone to be replaced")
+	}
+	if len(cloned.Commits) != 3 {
+		t.Errorf("expected 3 commits, got %d", len(cloned.Commits))
+	}
+}